@@ -0,0 +1,201 @@
+package dicomgraphics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"time"
+)
+
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// EncodeAPNG writes frames as an animated PNG, showing each for its duration in delays.
+// The animation plays loops times, or repeats forever if loops is 0.
+// Frames are written as 8 bit greyscale if none of them has colour, otherwise as RGB,
+// and must all be the size of the first.
+func EncodeAPNG(w io.Writer, frames []image.Image, delays []time.Duration, loops int) error {
+	if len(frames) == 0 {
+		return errors.New("apng: no frames to write")
+	}
+	if len(delays) != len(frames) {
+		return errors.New("apng: there must be a delay for each frame")
+	}
+	grey := true
+	for _, f := range frames {
+		if !isGrey(f) {
+			grey = false
+			break
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	if _, err := out.WriteString(pngSignature); err != nil {
+		return err
+	}
+	size := frames[0].Bounds().Size()
+	var header []byte
+	seq := uint32(0)
+	for i, f := range frames {
+		if f.Bounds().Size() != size {
+			return fmt.Errorf("apng: frame %d is not the size of the first", i+1)
+		}
+		ihdr, idat, err := pngChunks(eightBitImage(f, grey))
+		if err != nil {
+			return err
+		}
+
+		if i == 0 {
+			header = ihdr
+			actl := make([]byte, 8)
+			binary.BigEndian.PutUint32(actl, uint32(len(frames)))
+			binary.BigEndian.PutUint32(actl[4:], uint32(loops))
+			if err = writeChunk(out, "IHDR", ihdr); err != nil {
+				return err
+			}
+			if err = writeChunk(out, "acTL", actl); err != nil {
+				return err
+			}
+		} else if !bytes.Equal(ihdr, header) {
+			return fmt.Errorf("apng: frame %d has a different colour type to the first", i+1)
+		}
+
+		num, den := apngDelay(delays[i])
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl, seq)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(size.X))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(size.Y))
+		// the frame is placed at 0,0, and is neither disposed nor blended as it covers the previous frame
+		binary.BigEndian.PutUint16(fctl[20:], num)
+		binary.BigEndian.PutUint16(fctl[22:], den)
+		if err = writeChunk(out, "fcTL", fctl); err != nil {
+			return err
+		}
+		seq++
+
+		for _, data := range idat {
+			if i == 0 {
+				err = writeChunk(out, "IDAT", data)
+			} else {
+				// frames after the first are stored in fdAT chunks, which are IDAT data after a sequence number
+				fdat := make([]byte, 4, 4+len(data))
+				binary.BigEndian.PutUint32(fdat, seq)
+				err = writeChunk(out, "fdAT", append(fdat, data...))
+				seq++
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := writeChunk(out, "IEND", nil); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// apngDelay returns a frame duration as the numerator and denominator of a fraction of a second.
+func apngDelay(d time.Duration) (uint16, uint16) {
+	if ms := d / time.Millisecond; ms <= 0xffff {
+		return uint16(ms), 1000
+	}
+	cs := d / (10 * time.Millisecond)
+	if cs > 0xffff {
+		cs = 0xffff
+	}
+	return uint16(cs), 100
+}
+
+// EightBit converts an image to 8 bits per sample, for formats such as JPEG and PNG, as greyscale unless it has colour
+// such as coloured overlays.
+func EightBit(img image.Image) image.Image {
+	if m := img.ColorModel(); m == color.GrayModel || m == color.Gray16Model {
+		return eightBitImage(img, true)
+	}
+	return eightBitImage(img, isGrey(img))
+}
+
+// eightBitImage converts an image to 8 bit greyscale, or RGBA if grey is false, so that images
+// that are encoded together have the same colour type.
+func eightBitImage(img image.Image, grey bool) image.Image {
+	b := img.Bounds()
+	var dst draw.Image = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	if grey {
+		dst = image.NewGray(dst.Bounds())
+	}
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// pngChunks encodes an image as a PNG, returning the contents of its header and image data chunks.
+func pngChunks(img image.Image) ([]byte, [][]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, nil, err
+	}
+
+	data := buf.Bytes()[len(pngSignature):]
+	var ihdr []byte
+	var idat [][]byte
+	for len(data) >= 12 {
+		length := binary.BigEndian.Uint32(data)
+		kind, contents := string(data[4:8]), data[8:8+length]
+		switch kind {
+		case "IHDR":
+			ihdr = contents
+		case "IDAT":
+			idat = append(idat, contents)
+		}
+		data = data[12+length:]
+	}
+	return ihdr, idat, nil
+}
+
+func writeChunk(w io.Writer, kind string, data []byte) error {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(data)))
+	copy(header[4:], kind)
+	crc := crc32.NewIEEE()
+	_, _ = crc.Write(header[4:])
+	_, _ = crc.Write(data)
+
+	for _, b := range [][]byte{header, data, crc.Sum(nil)} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isGrey returns true if every pixel of an image has equal red, green and blue.
+func isGrey(img image.Image) bool {
+	switch src := img.(type) {
+	case *image.Gray, *image.Gray16:
+		return true
+	case *image.RGBA:
+		for i := 0; i+2 < len(src.Pix); i += 4 {
+			if src.Pix[i] != src.Pix[i+1] || src.Pix[i] != src.Pix[i+2] {
+				return false
+			}
+		}
+		return true
+	}
+
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if r, g, bl, _ := img.At(x, y).RGBA(); r != g || r != bl {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,197 @@
+package dicomgraphics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"time"
+)
+
+// AVI header flags and sizes used by EncodeAVI, from the AVI 1.0 (RIFF) format.
+const (
+	aviHasIndex  = 0x10
+	aviKeyFrame  = 0x10
+	aviChunkID   = "00dc"
+	aviIndexSize = 16
+)
+
+// aviMainHeader is the avih chunk, describing the whole file.
+type aviMainHeader struct {
+	MicroSecPerFrame, MaxBytesPerSec, PaddingGranularity, Flags uint32
+	TotalFrames, InitialFrames, Streams, SuggestedBufferSize    uint32
+	Width, Height                                               uint32
+	Reserved                                                    [4]uint32
+}
+
+// aviStreamHeader is the strh chunk, describing the video stream.
+type aviStreamHeader struct {
+	Type, Handler                  [4]byte
+	Flags                          uint32
+	Priority, Language             uint16
+	InitialFrames, Scale, Rate     uint32
+	Start, Length, SuggestedBuffer uint32
+	Quality, SampleSize            uint32
+	Frame                          [4]int16
+}
+
+// aviBitmapHeader is the strf chunk, a BITMAPINFOHEADER describing the frames of the stream.
+type aviBitmapHeader struct {
+	Size                        uint32
+	Width, Height               int32
+	Planes, BitCount            uint16
+	Compression                 [4]byte
+	SizeImage                   uint32
+	XPerMeter, YPerMeter        int32
+	ColorsUsed, ColorsImportant uint32
+}
+
+// EncodeAVI writes frames as a Motion JPEG AVI file, encoding each frame as a JPEG of the given quality.
+// AVI plays at a constant rate, so the rate is set by the shortest of the delays and longer frames are repeated
+// to last their duration. Frames must all be the size of the first.
+func EncodeAVI(w io.Writer, frames []image.Image, delays []time.Duration, quality int) error {
+	if len(frames) == 0 {
+		return errors.New("avi: no frames to write")
+	}
+	if len(delays) != len(frames) {
+		return errors.New("avi: there must be a delay for each frame")
+	}
+
+	tick := time.Duration(0)
+	for _, d := range delays {
+		if d > 0 && (tick == 0 || d < tick) {
+			tick = d
+		}
+	}
+	if tick < time.Millisecond {
+		tick = time.Second / DefaultFrameRate
+	}
+
+	size := frames[0].Bounds().Size()
+	var jpegs [][]byte
+	var stream []int // the frames that make up the stream, as indexes of jpegs
+	largest := 0
+	for i, f := range frames {
+		if f.Bounds().Size() != size {
+			return fmt.Errorf("avi: frame %d is not the size of the first", i+1)
+		}
+		var buf bytes.Buffer
+		// frames are converted to RGBA so that they are encoded in colour, which players expect
+		if err := jpeg.Encode(&buf, Transform{}.Apply(f), &jpeg.Options{Quality: quality}); err != nil {
+			return err
+		}
+		jpegs = append(jpegs, buf.Bytes())
+		if buf.Len() > largest {
+			largest = buf.Len()
+		}
+
+		repeats := int((delays[i] + tick/2) / tick)
+		if repeats < 1 {
+			repeats = 1
+		}
+		for r := 0; r < repeats; r++ {
+			stream = append(stream, i)
+		}
+	}
+
+	moviSize := 4
+	for _, i := range stream {
+		moviSize += 8 + len(jpegs[i]) + len(jpegs[i])%2
+	}
+	const headerSize = 4 + (8 + 4 + (8 + 56) + (8 + 4 + (8 + 56) + (8 + 40))) + 8
+	riffSize := int64(headerSize) + int64(moviSize) + 8 + int64(len(stream)*aviIndexSize)
+	if riffSize > 1<<32-1 {
+		return errors.New("avi: the frames are too large for an AVI file")
+	}
+
+	le := binary.LittleEndian
+	var header bytes.Buffer
+	header.WriteString("RIFF")
+	_ = binary.Write(&header, le, uint32(riffSize))
+	header.WriteString("AVI LIST")
+	_ = binary.Write(&header, le, uint32(4+(8+56)+(8+4+(8+56)+(8+40))))
+	header.WriteString("hdrlavih")
+	_ = binary.Write(&header, le, uint32(56))
+	tickMicros := uint32(tick / time.Microsecond)
+	_ = binary.Write(&header, le, aviMainHeader{
+		MicroSecPerFrame:    tickMicros,
+		MaxBytesPerSec:      uint32(int64(largest) * int64(time.Second) / int64(tick)),
+		Flags:               aviHasIndex,
+		TotalFrames:         uint32(len(stream)),
+		Streams:             1,
+		SuggestedBufferSize: uint32(largest + 8),
+		Width:               uint32(size.X),
+		Height:              uint32(size.Y),
+	})
+
+	header.WriteString("LIST")
+	_ = binary.Write(&header, le, uint32(4+(8+56)+(8+40)))
+	header.WriteString("strlstrh")
+	_ = binary.Write(&header, le, uint32(56))
+	// the stream runs at Rate / Scale frames per second
+	_ = binary.Write(&header, le, aviStreamHeader{
+		Type:            [4]byte{'v', 'i', 'd', 's'},
+		Handler:         [4]byte{'M', 'J', 'P', 'G'},
+		Scale:           tickMicros,
+		Rate:            uint32(time.Second / time.Microsecond),
+		Length:          uint32(len(stream)),
+		SuggestedBuffer: uint32(largest + 8),
+		Quality:         0xffffffff, // the default quality
+		Frame:           [4]int16{0, 0, int16(size.X), int16(size.Y)},
+	})
+	header.WriteString("strf")
+	_ = binary.Write(&header, le, uint32(40))
+	_ = binary.Write(&header, le, aviBitmapHeader{
+		Size:        40,
+		Width:       int32(size.X),
+		Height:      int32(size.Y),
+		Planes:      1,
+		BitCount:    24,
+		Compression: [4]byte{'M', 'J', 'P', 'G'},
+		SizeImage:   uint32(size.X * size.Y * 3),
+	})
+
+	header.WriteString("LIST")
+	_ = binary.Write(&header, le, uint32(moviSize))
+	header.WriteString("movi")
+
+	out := bufio.NewWriter(w)
+	if _, err := out.Write(header.Bytes()); err != nil {
+		return err
+	}
+	var index bytes.Buffer
+	index.WriteString("idx1")
+	_ = binary.Write(&index, le, uint32(len(stream)*aviIndexSize))
+	offset := uint32(4) // index offsets are from the start of the movi list type
+	for _, i := range stream {
+		data := jpegs[i]
+		var chunk bytes.Buffer
+		chunk.WriteString(aviChunkID)
+		_ = binary.Write(&chunk, le, uint32(len(data)))
+		if _, err := out.Write(chunk.Bytes()); err != nil {
+			return err
+		}
+		if _, err := out.Write(data); err != nil {
+			return err
+		}
+		// chunks are padded to an even length
+		if len(data)%2 == 1 {
+			if err := out.WriteByte(0); err != nil {
+				return err
+			}
+		}
+
+		index.WriteString(aviChunkID)
+		_ = binary.Write(&index, le, []uint32{aviKeyFrame, offset, uint32(len(data))})
+		offset += uint32(8 + len(data) + len(data)%2)
+	}
+
+	if _, err := out.Write(index.Bytes()); err != nil {
+		return err
+	}
+	return out.Flush()
+}
@@ -0,0 +1,180 @@
+package dicomgraphics
+
+import (
+	"image"
+	"image/color"
+	"time"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// DefaultFrameRate is the playback rate, in frames per second, used when a dataset has no timing information.
+const DefaultFrameRate = 10
+
+// FrameTimes returns how long each of the given number of frames should be displayed for.
+// The timing is read from Frame Time Vector, Frame Time, Cine Rate or Recommended Display Frame Rate,
+// in that order of preference. If none are present the returned bool is false and the frames
+// are timed at DefaultFrameRate.
+func FrameTimes(data *dicom.Dataset, frames int) ([]time.Duration, bool) {
+	times := make([]time.Duration, frames)
+	if frames == 0 {
+		return times, false
+	}
+
+	// Each entry of the vector is the increment since the previous frame, so the first is always 0.
+	if vector := TagFloats(data, tag.FrameTimeVector); len(vector) == frames && frames > 1 {
+		for i := 0; i < frames-1; i++ {
+			times[i] = milliseconds(vector[i+1])
+		}
+		times[frames-1] = times[frames-2]
+		return times, true
+	}
+
+	ms := 1000.0 / DefaultFrameRate
+	found := false
+	if t := TagFloats(data, tag.FrameTime); len(t) > 0 && t[0] > 0 {
+		ms, found = t[0], true
+	} else if r := TagFloats(data, tag.CineRate); len(r) > 0 && r[0] > 0 {
+		ms, found = 1000/r[0], true
+	} else if r := TagFloats(data, tag.RecommendedDisplayFrameRate); len(r) > 0 && r[0] > 0 {
+		ms, found = 1000/r[0], true
+	}
+
+	for i := range times {
+		times[i] = milliseconds(ms)
+	}
+	return times, found
+}
+
+// FrameRate returns the average playback rate of a dataset in frames per second.
+func FrameRate(data *dicom.Dataset, frames int) float64 {
+	times, _ := FrameTimes(data, frames)
+	total := time.Duration(0)
+	for _, t := range times {
+		total += t
+	}
+	if total <= 0 {
+		return DefaultFrameRate
+	}
+
+	return float64(len(times)) / total.Seconds()
+}
+
+// CineOptions control how RenderCine draws the slices of a cine loop.
+type CineOptions struct {
+	// Overlays draws the overlay planes of each slice, in OverlayColor or DefaultOverlayColor if it is nil.
+	Overlays     bool
+	OverlayColor color.Color
+	// Annotate burns orientation markers and the four-corner text of Corners into each frame.
+	Annotate bool
+	Corners  CornerLayout
+	// MaxSize is the largest width or height of a frame, larger slices are scaled down. 0 means no limit.
+	MaxSize int
+	// FrameRate replaces the timing of the slices with a fixed rate, in frames per second, if it is more than 0.
+	FrameRate float64
+}
+
+// Cine is a sequence of rendered frames along with how long each should be shown.
+type Cine struct {
+	Frames []*image.RGBA
+	Times  []time.Duration
+	// Timed is set if the times were read from the dataset, otherwise the frames are timed at DefaultFrameRate.
+	Timed bool
+	// Color is set if any frame was drawn from colour pixel data.
+	Color bool
+	// Level and Width are the window that greyscale frames were drawn at.
+	Level, Width int16
+}
+
+// RenderCine draws the slices of a cine loop, such as the frames of a multi-frame image or the images of a series,
+// at the default window of the first slice. Slices with three samples per pixel are drawn in colour.
+// Timing is read from the dataset when every slice is a frame of the same one.
+func RenderCine(slices []*Slice, opts CineOptions) *Cine {
+	c := &Cine{Level: 40, Width: 380}
+	if len(slices) == 0 {
+		return c
+	}
+	first := slices[0].Data
+	if l, w, ok := DefaultWindow(first); ok {
+		c.Level, c.Width = l, w
+	}
+	if slices[len(slices)-1].Data == first {
+		c.Times, c.Timed = FrameTimes(first, len(slices))
+	} else {
+		c.Times = make([]time.Duration, len(slices))
+		for i := range c.Times {
+			c.Times[i] = milliseconds(1000.0 / DefaultFrameRate)
+		}
+	}
+
+	if opts.FrameRate > 0 {
+		for i := range c.Times {
+			c.Times[i] = time.Duration(float64(time.Second) / opts.FrameRate)
+		}
+	}
+
+	r := newSliceRenderer(c.Level, c.Width, opts.Overlays, opts.OverlayColor)
+	for i, slice := range slices {
+		src, isColor := r.render(slice)
+		c.Color = c.Color || isColor
+
+		scaled, _ := ScaleToFit(src, opts.MaxSize, opts.MaxSize)
+		frame := Transform{}.Apply(scaled)
+		if opts.Annotate {
+			// text is drawn after scaling so that it stays legible
+			info := CornerInfo{Data: slice.Data, Plane: slice.Plane, Index: i, Count: len(slices), Level: c.Level,
+				Width: c.Width}
+			orientation, _ := ImageOrientation(slice.Data, slice.Plane)
+			DrawCorners(frame, opts.Corners.Text(info), orientation)
+		}
+		c.Frames = append(c.Frames, frame)
+	}
+	return c
+}
+
+// Images returns the frames of the cine loop as images, as taken by EncodeAPNG and EncodeAVI.
+func (c *Cine) Images() []image.Image {
+	images := make([]image.Image, len(c.Frames))
+	for i, frame := range c.Frames {
+		images[i] = frame
+	}
+	return images
+}
+
+// sliceRenderer draws slices at one window, reading the overlays of each dataset once.
+type sliceRenderer struct {
+	level, width int16
+	overlays     bool
+	overlayColor color.Color
+
+	overlayData map[*dicom.Dataset][]*Overlay
+}
+
+func newSliceRenderer(level, width int16, overlays bool, overlayColor color.Color) *sliceRenderer {
+	return &sliceRenderer{level: level, width: width, overlays: overlays, overlayColor: overlayColor,
+		overlayData: make(map[*dicom.Dataset][]*Overlay)}
+}
+
+// render draws a slice with its shutter and overlays, returning true if it was drawn from colour pixel data.
+func (r *sliceRenderer) render(slice *Slice) (image.Image, bool) {
+	if _, ok := r.overlayData[slice.Data]; !ok && r.overlays {
+		r.overlayData[slice.Data] = ParseOverlays(slice.Data)
+	}
+
+	img := NewDICOMImage(slice.Frame, r.level, r.width)
+	img.SetRescale(Rescale(slice.Data))
+	img.SetBitsStored(BitsStored(slice.Data))
+	img.SetShutter(ParseFrameShutter(slice.Data, slice.Index, slice.Frame))
+	img.SetOverlayColor(r.overlayColor)
+	img.SetOverlays(r.overlayData[slice.Data], slice.Index)
+	if rgb, ok := ColorFrame(slice); ok {
+		img.drawOver(rgb)
+		return rgb, true
+	}
+	return img, false
+}
+
+func milliseconds(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
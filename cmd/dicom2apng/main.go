@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"runtime"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+
+	"github.com/fynelabs/dicomgraphics"
+	"github.com/fynelabs/dicomgraphics/internal/cli"
+)
+
+// converter holds the options that are applied to each animation.
+type converter struct {
+	opts  dicomgraphics.CineOptions
+	loop  int
+	names dicomgraphics.OutputNames
+}
+
+// convert writes the slices of a series to an animated PNG, at the window of the first slice.
+func (c *converter) convert(src *dicomgraphics.SeriesSource) error {
+	if src.Len() == 0 {
+		return errors.New("no images found")
+	}
+
+	cine := dicomgraphics.RenderCine(src.Slices, c.opts)
+	pngPath, err := c.names.Path(src.Path, src.Slices[0].Data, map[string]string{"Name": src.Name})
+	if err != nil {
+		return err
+	}
+	err = dicomgraphics.WriteFile(pngPath, func(w io.Writer) error {
+		return dicomgraphics.EncodeAPNG(w, cine.Images(), cine.Times, c.loop)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Written", len(cine.Frames), "frames to", pngPath, "at", cine.Level, "width", cine.Width)
+	return nil
+}
+
+func main() {
+	outDir := ""
+	name := "{Name}"
+	workers := runtime.NumCPU()
+	loop := 0
+	series := false
+	cine := cli.AddCineFlags(flag.CommandLine)
+	flag.StringVar(&outDir, "out", outDir, "The directory to write to, instead of beside each input file")
+	flag.StringVar(&name, "name", name, "The output file name, with fields such as {PatientID}, {SeriesNumber:04} "+
+		"or {Name} (the input name) filled in and '/' separating directories")
+	flag.IntVar(&workers, "workers", workers, "The number of files to convert at once")
+	flag.IntVar(&loop, "loop", loop, "The number of times to play the animation, 0 repeats it forever")
+	flag.BoolVar(&series, "series", series, "Write each series of single frame images as one animation, "+
+		"sorted by instance number, rather than an animation per file")
+	flag.Parse()
+
+	if len(flag.Args()) == 0 {
+		log.Println("Must pass a parameter - the files, directories or patterns to convert")
+		return
+	}
+	if loop < 0 {
+		log.Println("Loop count must not be negative")
+		return
+	}
+	switch ext := strings.ToLower(dicomgraphics.TemplateExt(name)); ext {
+	case "":
+		name += ".png"
+	case ".png", ".apng":
+	default:
+		log.Println("Unsupported file type " + ext + ", the name must end in .png")
+		return
+	}
+
+	c := &converter{loop: loop, names: dicomgraphics.OutputNames{Dir: outDir, Template: name}}
+	var err error
+	if c.opts, err = cine.Parse(); err != nil {
+		log.Println(err)
+		return
+	}
+
+	// check the template fields before reading any files
+	if _, err = dicomgraphics.ExpandName(name, &dicom.Dataset{}, map[string]string{"Name": ""}); err != nil {
+		log.Println(err)
+		return
+	}
+
+	files := dicomgraphics.ListFiles(flag.Args(), func(path string, err error) {
+		log.Println("Error reading", path+":", err)
+	})
+	dicomgraphics.ConvertSeries(files, workers, series, c.convert, func(path string, err error) {
+		log.Println("Error converting", path+":", err)
+	})
+}
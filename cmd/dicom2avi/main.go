@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image/jpeg"
+	"io"
+	"log"
+	"runtime"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+
+	"github.com/fynelabs/dicomgraphics"
+	"github.com/fynelabs/dicomgraphics/internal/cli"
+)
+
+// converter holds the options that are applied to each animation.
+type converter struct {
+	opts    dicomgraphics.CineOptions
+	quality int
+	names   dicomgraphics.OutputNames
+}
+
+// convert writes the slices of a series to a Motion JPEG AVI file, at the window of the first slice.
+func (c *converter) convert(src *dicomgraphics.SeriesSource) error {
+	if src.Len() == 0 {
+		return errors.New("no images found")
+	}
+
+	cine := dicomgraphics.RenderCine(src.Slices, c.opts)
+	aviPath, err := c.names.Path(src.Path, src.Slices[0].Data, map[string]string{"Name": src.Name})
+	if err != nil {
+		return err
+	}
+	err = dicomgraphics.WriteFile(aviPath, func(w io.Writer) error {
+		return dicomgraphics.EncodeAVI(w, cine.Images(), cine.Times, c.quality)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Written", len(cine.Frames), "frames to", aviPath, "at", cine.Level, "width", cine.Width)
+	return nil
+}
+
+func main() {
+	outDir := ""
+	name := "{Name}"
+	workers := runtime.NumCPU()
+	quality := jpeg.DefaultQuality
+	series := false
+	cine := cli.AddCineFlags(flag.CommandLine)
+	flag.StringVar(&outDir, "out", outDir, "The directory to write to, instead of beside each input file")
+	flag.StringVar(&name, "name", name, "The output file name, with fields such as {PatientID}, {SeriesNumber:04} "+
+		"or {Name} (the input name) filled in and '/' separating directories")
+	flag.IntVar(&workers, "workers", workers, "The number of files to convert at once")
+	flag.IntVar(&quality, "quality", quality, "The JPEG quality of each frame, from 1 to 100")
+	flag.BoolVar(&series, "series", series, "Write each series of single frame images as one video, "+
+		"sorted by instance number, rather than a video per file")
+	flag.Parse()
+
+	if len(flag.Args()) == 0 {
+		log.Println("Must pass a parameter - the files, directories or patterns to convert")
+		return
+	}
+	if quality < 1 || quality > 100 {
+		log.Println("Quality must be from 1 to 100")
+		return
+	}
+	switch ext := strings.ToLower(dicomgraphics.TemplateExt(name)); ext {
+	case "":
+		name += ".avi"
+	case ".avi":
+	default:
+		log.Println("Unsupported file type " + ext + ", the name must end in .avi")
+		return
+	}
+
+	c := &converter{quality: quality, names: dicomgraphics.OutputNames{Dir: outDir, Template: name}}
+	var err error
+	if c.opts, err = cine.Parse(); err != nil {
+		log.Println(err)
+		return
+	}
+
+	// check the template fields before reading any files
+	if _, err = dicomgraphics.ExpandName(name, &dicom.Dataset{}, map[string]string{"Name": ""}); err != nil {
+		log.Println(err)
+		return
+	}
+
+	files := dicomgraphics.ListFiles(flag.Args(), func(path string, err error) {
+		log.Println("Error reading", path+":", err)
+	})
+	dicomgraphics.ConvertSeries(files, workers, series, c.convert, func(path string, err error) {
+		log.Println("Error converting", path+":", err)
+	})
+}
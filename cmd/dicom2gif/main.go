@@ -1,97 +1,153 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"image"
+	"image/color"
 	"image/color/palette"
 	"image/gif"
+	"io"
 	"log"
-	"os"
-	"strconv"
+	"runtime"
+	"strings"
+	"time"
 
-	"github.com/suyashkumar/dicom/pkg/frame"
 	"golang.org/x/image/draw"
 
 	"github.com/suyashkumar/dicom"
-	"github.com/suyashkumar/dicom/pkg/tag"
 
 	"github.com/fynelabs/dicomgraphics"
+	"github.com/fynelabs/dicomgraphics/internal/cli"
 )
 
-func findImage(data dicom.Dataset) ([]frame.NativeFrame, int16, int16) {
-	var frames []frame.NativeFrame
-	level := int16(40)
-	width := int16(380)
-	for _, elem := range data.Elements {
-		if elem.Tag == tag.PixelData {
-			slices := elem.Value.GetValue().(dicom.PixelDataInfo).Frames
-
-			if len(slices) == 0 {
-				panic("No images found")
-			}
-
-			for _, slice := range slices {
-				frames = append(frames, slice.NativeData)
-			}
-		} else if elem.Tag == tag.WindowCenter {
-			l, _ := strconv.Atoi(fmt.Sprintf("%v", elem.Value))
-			level = int16(l)
-		} else if elem.Tag == tag.WindowWidth {
-			l, _ := strconv.Atoi(fmt.Sprintf("%v", elem.Value))
-			width = int16(l)
-		}
+// greyPalette returns 256 levels of grey. If the overlay colour is not grey it replaces the darkest grey
+// above black, so that overlays keep their colour.
+func greyPalette(overlay color.Color) color.Palette {
+	p := make(color.Palette, 256)
+	for i := range p {
+		p[i] = color.Gray{Y: uint8(i)}
+	}
+	if r, g, b, _ := overlay.RGBA(); r != g || g != b {
+		p[1] = overlay
 	}
+	return p
+}
 
-	return frames, level, width
+// converter holds the options that are applied to each animation.
+type converter struct {
+	opts   dicomgraphics.CineOptions
+	dither bool
+	loop   int
+	names  dicomgraphics.OutputNames
 }
 
-func main() {
-	if len(os.Args) != 2 {
-		log.Println("Must pass a parameter - the file to convert")
-		return
+// convert writes the slices of a series to a gif, at the window of the first slice.
+func (c *converter) convert(src *dicomgraphics.SeriesSource) error {
+	if src.Len() == 0 {
+		return errors.New("no images found")
 	}
 
-	path := os.Args[1]
-	// TODO support a directory list as well
-	data, err := dicom.ParseFile(path, nil)
-	if err != nil {
-		log.Println("Error parsing " + path)
-		return
+	cine := dicomgraphics.RenderCine(src.Slices, c.opts)
+	pal := greyPalette(c.opts.OverlayColor)
+	if cine.Color {
+		pal = palette.Plan9
 	}
+	var images []*image.Paletted
+	var delays []int
+	for i, frame := range cine.Frames {
+		img := image.NewPaletted(frame.Bounds(), pal)
+		if c.dither {
+			draw.FloydSteinberg.Draw(img, img.Bounds(), frame, image.ZP)
+		} else {
+			draw.Copy(img, image.ZP, frame, frame.Bounds(), draw.Src, nil)
+		}
 
-	frames, level, width := findImage(data)
-	if frames == nil {
-		log.Println("No images found")
-		return
+		images = append(images, img)
+		// GIF delays are in 100ths of a second, and most decoders play shorter delays than 2 at 10 frames per second
+		delay := int((cine.Times[i] + 5*time.Millisecond) / (10 * time.Millisecond))
+		if delay < 2 {
+			delay = 2
+		}
+		delays = append(delays, delay)
 	}
 
-	gifPath := path[:len(path)-3] + "gif"
-	f, err := os.Create(gifPath)
+	gifPath, err := c.names.Path(src.Path, src.Slices[0].Data, map[string]string{"Name": src.Name})
 	if err != nil {
-		panic(err)
+		return err
+	}
+	err = dicomgraphics.WriteFile(gifPath, func(w io.Writer) error {
+		return gif.EncodeAll(w, &gif.GIF{Image: images, Delay: delays, LoopCount: c.loop})
+	})
+	if err != nil {
+		return err
 	}
 
-	var images []*image.Paletted
-	var delays []int
-	for _, frame := range frames {
-		src := dicomgraphics.NewDICOMImage(&frame, level, width)
-		img := image.NewPaletted(src.Bounds(), palette.WebSafe)
-		draw.Copy(img, image.ZP, src, src.Bounds(), draw.Src, nil)
+	fmt.Println("Written", len(images), "frames to", gifPath, "at", cine.Level, "width", cine.Width)
+	return nil
+}
 
-		images = append(images, img)
-		delays = append(delays, 0)
+func main() {
+	outDir := ""
+	name := "{Name}"
+	workers := runtime.NumCPU()
+	dither := false
+	loop := 0
+	series := false
+	cine := cli.AddCineFlags(flag.CommandLine)
+	flag.StringVar(&outDir, "out", outDir, "The directory to write to, instead of beside each input file")
+	flag.StringVar(&name, "name", name, "The output file name, with fields such as {PatientID}, {SeriesNumber:04} "+
+		"or {Name} (the input name) filled in and '/' separating directories")
+	flag.IntVar(&workers, "workers", workers, "The number of files to convert at once")
+	flag.BoolVar(&dither, "dither", dither, "Use Floyd-Steinberg dithering to smooth the 256 grey levels")
+	flag.IntVar(&loop, "loop", loop, "The number of times to play the animation, 0 repeats it forever")
+	flag.BoolVar(&series, "series", series, "Write each series of single frame images as one animation, "+
+		"sorted by instance number, rather than an animation per file")
+	flag.Parse()
+
+	if len(flag.Args()) == 0 {
+		log.Println("Must pass a parameter - the files, directories or patterns to convert")
+		return
 	}
-	err = gif.EncodeAll(f, &gif.GIF{
-		Image: images,
-		Delay: delays,
-	})
-	if err != nil {
-		panic(err)
+	if loop < 0 {
+		log.Println("Loop count must not be negative")
+		return
 	}
-	err = f.Close()
-	if err != nil {
-		panic(err)
+	switch ext := strings.ToLower(dicomgraphics.TemplateExt(name)); ext {
+	case "":
+		name += ".gif"
+	case ".gif":
+	default:
+		log.Println("Unsupported file type " + ext + ", the name must end in .gif")
+		return
 	}
 
-	fmt.Println("Written", len(images), "frames to", gifPath, "at", level, "width", width)
+	c := &converter{dither: dither, names: dicomgraphics.OutputNames{Dir: outDir, Template: name}}
+	// a gif loop count is the number of repeats after the first play, with -1 meaning play once
+	switch loop {
+	case 0:
+	case 1:
+		c.loop = -1
+	default:
+		c.loop = loop - 1
+	}
+	var err error
+	if c.opts, err = cine.Parse(); err != nil {
+		log.Println(err)
+		return
+	}
+
+	// check the template fields before reading any files
+	if _, err = dicomgraphics.ExpandName(name, &dicom.Dataset{}, map[string]string{"Name": ""}); err != nil {
+		log.Println(err)
+		return
+	}
+
+	files := dicomgraphics.ListFiles(flag.Args(), func(path string, err error) {
+		log.Println("Error reading", path+":", err)
+	})
+	dicomgraphics.ConvertSeries(files, workers, series, c.convert, func(path string, err error) {
+		log.Println("Error converting", path+":", err)
+	})
 }
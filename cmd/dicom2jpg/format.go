@@ -0,0 +1,79 @@
+package main
+
+import (
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/fynelabs/dicomgraphics"
+)
+
+// format is a file type that frames can be written as.
+type format struct {
+	// exts are the file extensions of the format, the first is added to names that have none
+	exts []string
+	// values is set for 16 bit formats, which hold the pixel values rather than a windowed image
+	values bool
+	// multiPage formats write every frame of a file to a single output
+	multiPage bool
+	encode    func(w io.Writer, pages []image.Image, quality int) error
+}
+
+var (
+	// formatNames is the order formats are matched by extension, so the 8 bit formats are preferred
+	formatNames = []string{"jpg", "png", "tiff", "png16", "tiff16"}
+
+	formats = map[string]*format{
+		"jpg":    {exts: []string{".jpg", ".jpeg"}, encode: encodeJPEG},
+		"png":    {exts: []string{".png"}, encode: encodePNG},
+		"tiff":   {exts: []string{".tif", ".tiff"}, multiPage: true, encode: encodeTIFF},
+		"png16":  {exts: []string{".png"}, values: true, encode: encodePNG},
+		"tiff16": {exts: []string{".tif", ".tiff"}, values: true, multiPage: true, encode: encodeTIFF},
+	}
+)
+
+// findFormat returns the format with the given name, or if it is empty the format of an extension.
+// Names without an extension are written as jpg.
+func findFormat(name, ext string) (*format, bool) {
+	ext = strings.ToLower(ext)
+	if name == "" {
+		if ext == "" {
+			return formats["jpg"], true
+		}
+		for _, n := range formatNames {
+			if formats[n].hasExt(ext) {
+				return formats[n], true
+			}
+		}
+		return nil, false
+	}
+
+	f, ok := formats[strings.ToLower(name)]
+	if !ok || (ext != "" && !f.hasExt(ext)) {
+		return nil, false
+	}
+	return f, true
+}
+
+func (f *format) hasExt(ext string) bool {
+	for _, e := range f.exts {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeJPEG(w io.Writer, pages []image.Image, quality int) error {
+	return jpeg.Encode(w, pages[0], &jpeg.Options{Quality: quality})
+}
+
+func encodePNG(w io.Writer, pages []image.Image, _ int) error {
+	return png.Encode(w, pages[0])
+}
+
+func encodeTIFF(w io.Writer, pages []image.Image, _ int) error {
+	return dicomgraphics.EncodeTIFF(w, pages)
+}
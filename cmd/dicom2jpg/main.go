@@ -1,77 +1,402 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"image"
+	"image/color"
 	"image/jpeg"
+	"io"
 	"log"
-	"os"
+	"path/filepath"
+	"runtime"
 	"strconv"
+	"strings"
 
 	"github.com/fynelabs/dicomgraphics"
+	"github.com/fynelabs/dicomgraphics/internal/cli"
 	"github.com/suyashkumar/dicom"
 	"github.com/suyashkumar/dicom/pkg/frame"
 	"github.com/suyashkumar/dicom/pkg/tag"
 )
 
-func findImage(data dicom.Dataset) (*frame.NativeFrame, int16, int16) {
-	var frame *frame.NativeFrame
-	level := int16(40)
-	width := int16(380)
+// errEncapsulated is returned for a file whose selected frames are all compressed.
+var errEncapsulated = errors.New("compressed (encapsulated) pixel data is not supported")
+
+// findFrames returns the frames of the pixel data in a dataset.
+// Encapsulated frames cannot be drawn, so they are nil, keeping the others at their frame number.
+func findFrames(data *dicom.Dataset) []*frame.NativeFrame {
+	var frames []*frame.NativeFrame
 	for _, elem := range data.Elements {
-		if elem.Tag == tag.PixelData {
-			frames := elem.Value.GetValue().(dicom.PixelDataInfo).Frames
+		if elem.Tag != tag.PixelData {
+			continue
+		}
 
-			if len(frames) == 0 {
-				panic("No images found")
-			} else if len(frames) > 1 {
-				log.Println("Many images found, displaying only first element")
+		info := elem.Value.GetValue().(dicom.PixelDataInfo)
+		for i := range info.Frames {
+			if info.Frames[i].Encapsulated {
+				frames = append(frames, nil)
+				continue
 			}
-
-			frame = &frames[0].NativeData
-		} else if elem.Tag == tag.WindowCenter {
-			l, _ := strconv.Atoi(fmt.Sprintf("%v", elem.Value))
-			level = int16(l)
-		} else if elem.Tag == tag.WindowWidth {
-			l, _ := strconv.Atoi(fmt.Sprintf("%v", elem.Value))
-			width = int16(l)
+			frames = append(frames, &info.Frames[i].NativeData)
 		}
 	}
 
-	return frame, level, width
+	return frames
 }
 
-func main() {
-	if len(os.Args) != 2 {
-		log.Println("Must pass a parameter - the file to convert")
-		return
+// annotateImage draws orientation markers and four-corner text over a frame of an image.
+func annotateImage(dst *image.RGBA, data *dicom.Dataset, index, count int, layout dicomgraphics.CornerLayout,
+	level, width int16, t dicomgraphics.Transform) {
+	plane := dicomgraphics.ImagePlanes(data, count)[index]
+	info := dicomgraphics.CornerInfo{Data: data, Plane: plane, Index: index, Count: count, Level: level, Width: width}
+	orientation, _ := dicomgraphics.ImageOrientation(data, plane)
+	dicomgraphics.DrawCorners(dst, layout.Text(info), orientation.Transform(t.Flip, t.Rotation))
+}
+
+// parseTransform reads the -rotate and -flip options, where flip is "h", "v" or both.
+func parseTransform(rotate int, flip string) (dicomgraphics.Transform, error) {
+	rotation, ok := dicomgraphics.ParseRotation(rotate)
+	if !ok {
+		return dicomgraphics.Transform{}, errors.New("rotation must be a multiple of 90 degrees")
+	}
+
+	t := dicomgraphics.Transform{Rotation: rotation}
+	for _, axis := range strings.ToLower(flip) {
+		switch axis {
+		case 'h':
+			t = t.FlipHorizontal()
+		case 'v':
+			t = t.FlipVertical()
+		default:
+			return t, errors.New("flip must be h, v or hv")
+		}
 	}
+	return t, nil
+}
+
+// source is a file being converted, with the values read from it that apply to every frame.
+type source struct {
+	path   string
+	data   *dicom.Dataset
+	frames []*frame.NativeFrame
 
-	path := os.Args[1]
+	level, width int16
+	overlays     []*dicomgraphics.Overlay
+}
+
+// converter holds the options that are applied to each file and frame.
+type converter struct {
+	frames              string
+	window              *cli.WindowFlags
+	overlays, invert    bool
+	overlayColor        color.Color
+	state               *dicomgraphics.PresentationState
+	view                dicomgraphics.Transform
+	annotate, scaleBar  bool
+	layout              dicomgraphics.CornerLayout
+	maxWidth, maxHeight int
+	quality             int
+	format              *format
+	// rescaled writes modality values rather than stored values to 16 bit formats
+	rescaled bool
+
+	// frameNames numbers the frames of a multi-frame image written to single page files,
+	// such as image_0003.jpg, when the name template does not include the {Frame} field
+	names, frameNames dicomgraphics.OutputNames
+	unique            dicomgraphics.UniqueNames
+}
+
+// convertFile writes the selected frames of a file, returning the first error.
+func (c *converter) convertFile(path string) error {
 	data, err := dicom.ParseFile(path, nil)
 	if err != nil {
-		log.Println("Error parsing " + path)
-		return
+		return err
 	}
 
-	frame, level, width := findImage(data)
-	if frame == nil {
-		log.Println("No image found")
-		return
+	src := &source{path: path, data: &data, frames: findFrames(&data)}
+	if len(src.frames) == 0 {
+		return errors.New("no image found")
 	}
-
-	jpegPath := path[:len(path)-3] + "jpg"
-	f, err := os.Create(jpegPath)
+	selected, err := dicomgraphics.ParseFrames(c.frames, len(src.frames))
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	src.level, src.width = c.window.Window(&data)
+	if c.overlays {
+		src.overlays = dicomgraphics.ParseOverlays(&data)
+	}
+	if c.state != nil && !c.state.References(dicomgraphics.NewImageReference(&dicomgraphics.Slice{Data: &data})) {
+		log.Println("Presentation state does not reference " + path + ", applying it anyway")
 	}
-	err = jpeg.Encode(f, dicomgraphics.NewDICOMImage(frame, level, width), nil)
+
+	var pages []image.Image
+	var level, width int16
+	first, skipped := -1, 0
+	for _, index := range selected {
+		if src.frames[index] == nil {
+			skipped++
+			continue
+		}
+		if first < 0 {
+			first = index
+		}
+
+		var out image.Image
+		if c.format.values {
+			out = c.values(src, index)
+		} else {
+			out, level, width = c.convert(src, index)
+			out = dicomgraphics.EightBit(out)
+		}
+
+		pages = append(pages, out)
+		if !c.format.multiPage {
+			if err = c.write(src, index, pages, level, width); err != nil {
+				return err
+			}
+			pages = nil
+		}
+	}
+	if first < 0 {
+		return errEncapsulated
+	}
+	if skipped > 0 {
+		log.Println("Skipped", skipped, "compressed frames of", path)
+	}
+	if c.format.multiPage {
+		return c.write(src, first, pages, level, width)
+	}
+	return nil
+}
+
+// write saves the pages converted from a file, reporting the window they were drawn at.
+func (c *converter) write(src *source, index int, pages []image.Image, level, width int16) error {
+	names := &c.names
+	if len(src.frames) > 1 && !c.format.multiPage && !strings.Contains(names.Template, "{Frame") {
+		names = &c.frameNames
+	}
+	outPath, err := names.Path(src.path, src.data, map[string]string{
+		"Name":  strings.TrimSuffix(filepath.Base(src.path), filepath.Ext(src.path)),
+		"Frame": strconv.Itoa(index + 1),
+	})
 	if err != nil {
-		panic(err)
+		return err
 	}
-	err = f.Close()
+	err = dicomgraphics.WriteFile(outPath, func(w io.Writer) error {
+		return c.format.encode(w, pages, c.quality)
+	})
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	written := outPath
+	if len(pages) > 1 {
+		written = fmt.Sprint(len(pages), " frames to ", outPath)
+	}
+	switch {
+	case !c.format.values:
+		fmt.Println("Written", written, "at", level, "width", width)
+	case c.rescaled:
+		fmt.Println("Written", written, "with rescaled values")
+	default:
+		fmt.Println("Written", written, "with stored values")
+	}
+	return nil
+}
+
+// values returns the pixel values of a frame for a 16 bit format, with the view and size options applied.
+func (c *converter) values(src *source, index int) image.Image {
+	img := dicomgraphics.NewDICOMImage(src.frames[index], src.level, src.width)
+	img.SetRescale(dicomgraphics.Rescale(src.data))
+	img.SetBitsStored(dicomgraphics.BitsStored(src.data))
+
+	out, _ := dicomgraphics.ScaleToFit(c.view.ApplyGray16(img.Values(c.rescaled)), c.maxWidth, c.maxHeight)
+	return out
+}
+
+// convert renders a frame with the window, presentation state and display options,
+// returning the image and the window it was drawn at.
+func (c *converter) convert(src *source, index int) (image.Image, int16, int16) {
+	img := dicomgraphics.NewDICOMImage(src.frames[index], src.level, src.width)
+	img.SetRescale(dicomgraphics.Rescale(src.data))
+	img.SetBitsStored(dicomgraphics.BitsStored(src.data))
+	img.SetShutter(dicomgraphics.ParseFrameShutter(src.data, index, src.frames[index]))
+	img.SetInverse(c.invert)
+	if src.overlays != nil {
+		img.SetOverlayColor(c.overlayColor)
+		img.SetOverlays(src.overlays, index)
+	}
+
+	var out image.Image = img
+	// display is the orientation of the presentation state, if any, which the requested view is applied after
+	display := dicomgraphics.Transform{}
+	cal := dicomgraphics.PixelCalibration(src.data)
+	magnification := 1.0
+	if c.state != nil {
+		ref := dicomgraphics.NewImageReference(&dicomgraphics.Slice{Data: src.data, Index: index})
+		out = c.state.Render(img, ref, cal)
+		display = dicomgraphics.Transform{Rotation: c.state.Rotation, Flip: c.state.Flip}
+		if area, ok := c.state.DisplayedAreaFor(ref); ok && area.SizeMode == dicomgraphics.SizeMagnify &&
+			area.Magnification > 0 {
+			magnification = area.Magnification
+		}
+	}
+	level, width := img.WindowLevel(), img.WindowWidth()
+
+	if c.view != (dicomgraphics.Transform{}) {
+		out = c.view.Apply(out)
+	}
+	// text is drawn after scaling so that it stays legible
+	out, scale := dicomgraphics.ScaleToFit(out, c.maxWidth, c.maxHeight)
+	if c.annotate || c.scaleBar {
+		// copies the image to draw over if it has not been rendered already
+		dst := dicomgraphics.Transform{}.Apply(out)
+		if c.annotate {
+			annotateImage(dst, src.data, index, len(src.frames), c.layout, level, width, display.Then(c.view))
+		}
+		if c.scaleBar {
+			spacing := cal.ColumnSpacing
+			if display.Then(c.view).Swapped() {
+				spacing = cal.RowSpacing
+			}
+			dicomgraphics.DrawScaleBar(dst, spacing/magnification/scale)
+		}
+		out = dst
+	}
+	return out, level, width
+}
+
+func main() {
+	pstate := ""
+	overlays := true
+	overlayColor := "#ffffff"
+	annotate := false
+	corners := dicomgraphics.DefaultCornerLayout.String()
+	scaleBar := false
+	rotate, flip, invert := 0, "", false
+	frames := "all"
+	quality := jpeg.DefaultQuality
+	outDir := ""
+	maxWidth, maxHeight := 0, 0
+	name := "{Name}"
+	workers := runtime.NumCPU()
+	formatName, values := "", "stored"
+	flag.StringVar(&pstate, "pstate", pstate, "A grayscale presentation state file (GSPS) to apply to the image")
+	flag.BoolVar(&overlays, "overlays", overlays, "Draw the overlay planes of the image")
+	flag.StringVar(&overlayColor, "overlay-color", overlayColor, "The colour of overlay planes, as #rrggbb")
+	flag.BoolVar(&annotate, "annotate", annotate, "Burn orientation markers and four-corner text into the image")
+	flag.StringVar(&corners, "corners", corners, "The fields of each corner for -annotate, as comma separated lists for "+
+		"top left, top right, bottom left and bottom right separated by ';'")
+	flag.BoolVar(&scaleBar, "scalebar", scaleBar, "Burn a scale bar into the image, if its pixel spacing is known")
+	flag.IntVar(&rotate, "rotate", rotate, "Rotate the image clockwise by a multiple of 90 degrees")
+	flag.StringVar(&flip, "flip", flip, "Flip the image horizontally (h), vertically (v) or both (hv), after rotating")
+	flag.BoolVar(&invert, "invert", invert, "Invert the greyscale of the image")
+	flag.StringVar(&frames, "frames", frames, "The frames to write, as all, a frame number such as 3 or a range such as 1-10")
+	window := cli.AddWindowFlags(flag.CommandLine)
+	flag.IntVar(&quality, "quality", quality, "The JPEG quality, from 1 to 100")
+	flag.StringVar(&outDir, "out", outDir, "The directory to write to, instead of beside each input file")
+	flag.IntVar(&maxWidth, "max-width", maxWidth, "The largest width to write, larger images are scaled down")
+	flag.IntVar(&maxHeight, "max-height", maxHeight, "The largest height to write, larger images are scaled down")
+	flag.StringVar(&name, "name", name, "The output file name, with fields such as {PatientID}, {InstanceNumber:04}, "+
+		"{Frame} or {Name} (the input name) filled in and '/' separating directories")
+	flag.IntVar(&workers, "workers", workers, "The number of files to convert at once")
+	flag.StringVar(&formatName, "format", formatName, "The file type: jpg, png, tiff (one page per frame), "+
+		"or png16 and tiff16 for 16 bit pixel values, by default chosen from the -name extension or jpg")
+	flag.StringVar(&values, "values", values, "The values written by 16 bit formats, stored or rescaled")
+	flag.Parse()
+
+	if len(flag.Args()) == 0 {
+		log.Println("Must pass a parameter - the files, directories or patterns to convert")
+		return
+	}
+	if quality < 1 || quality > 100 {
+		log.Println("Quality must be from 1 to 100")
+		return
+	}
+	ext := dicomgraphics.TemplateExt(name)
+	f, ok := findFormat(formatName, ext)
+	if !ok {
+		switch {
+		case formatName == "":
+			log.Println("Unsupported file type " + ext + ", the name must end in .jpg, .png or .tif")
+		case formats[strings.ToLower(formatName)] == nil:
+			log.Println("Unknown format " + formatName + ", must be jpg, png, tiff, png16 or tiff16")
+		default:
+			log.Println("The name of " + formatName + " files must end in " +
+				strings.Join(formats[strings.ToLower(formatName)].exts, " or "))
+		}
+		return
+	}
+	if ext == "" {
+		name += f.exts[0]
+	}
+	if f.multiPage && strings.Contains(name, "{Frame") {
+		log.Println("The frames of a multi-page format are written to one file, so {Frame} cannot be used in its name")
+		return
+	}
+	if values != "stored" && values != "rescaled" {
+		log.Println("Unknown values " + values + ", must be stored or rescaled")
+		return
+	}
+
+	c := &converter{frames: frames, window: window, overlays: overlays, invert: invert, annotate: annotate,
+		scaleBar: scaleBar, maxWidth: maxWidth, maxHeight: maxHeight, quality: quality, format: f,
+		rescaled: values == "rescaled"}
+	c.names = dicomgraphics.OutputNames{Dir: outDir, Template: name, Names: &c.unique}
+	ext = dicomgraphics.TemplateExt(name)
+	c.frameNames = dicomgraphics.OutputNames{Dir: outDir, Template: strings.TrimSuffix(name, ext) + "_{Frame:04}" + ext,
+		Names: &c.unique}
+	// a window from the command line replaces that of the files and of any presentation state
+	if err := window.Parse(); err != nil {
+		log.Println(err)
+		return
+	}
+	if f.values && (pstate != "" || annotate || scaleBar || invert || window.Set()) {
+		log.Println("16 bit formats hold the pixel values, so -pstate, -annotate, -scalebar, -invert and windows " +
+			"cannot be used")
+		return
 	}
 
-	fmt.Println("Written", jpegPath, "at", level, "width", width)
+	var err error
+	if c.overlayColor, err = dicomgraphics.ParseColor(overlayColor); err != nil {
+		log.Println("Invalid overlay colour " + overlayColor)
+		return
+	}
+	if c.layout, err = dicomgraphics.ParseCornerLayout(corners); err != nil {
+		log.Println(err)
+		return
+	}
+	if c.view, err = parseTransform(rotate, flip); err != nil {
+		log.Println(err)
+		return
+	}
+	if pstate != "" {
+		if c.state, err = dicomgraphics.LoadPresentationState(pstate); err != nil {
+			log.Println("Error reading presentation state "+pstate+":", err)
+			return
+		}
+
+		c.state.Inverse = c.state.Inverse != invert
+		if window.Set() {
+			c.state.Windows = nil
+		}
+	}
+
+	// check the template fields before reading any files
+	if _, err = dicomgraphics.ExpandName(name, &dicom.Dataset{}, map[string]string{"Name": "", "Frame": "1"}); err != nil {
+		log.Println(err)
+		return
+	}
+
+	files := dicomgraphics.ListFiles(flag.Args(), func(path string, err error) {
+		log.Println("Error reading", path+":", err)
+	})
+	dicomgraphics.Parallel(workers, len(files), func(i int) {
+		if err := c.convertFile(files[i]); err != nil {
+			log.Println("Error converting", files[i]+":", err)
+		}
+	})
 }
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"runtime"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+
+	"github.com/fynelabs/dicomgraphics"
+	"github.com/fynelabs/dicomgraphics/internal/cli"
+)
+
+// film is a series, or the key images of a series, to print to a PDF.
+type film struct {
+	*dicomgraphics.SeriesSource
+	// slices are the images to print, with their positions in the series for the corner text
+	slices    []*dicomgraphics.Slice
+	positions []int
+}
+
+// converter holds the options that are applied to each film.
+type converter struct {
+	columns, rows int
+	paper         dicomgraphics.PaperSize
+	opts          dicomgraphics.FilmImageOptions
+	window        *cli.WindowFlags
+	names         dicomgraphics.OutputNames
+}
+
+// convert renders the images of a film and writes them to a PDF.
+func (c *converter) convert(f *film) error {
+	if len(f.slices) == 0 {
+		return errors.New("no images found")
+	}
+
+	opts := c.opts
+	if c.window.Set() {
+		opts.Level, opts.Width = c.window.Window(f.slices[0].Data)
+	}
+
+	images := make([]image.Image, len(f.slices))
+	for i, slice := range f.slices {
+		images[i] = dicomgraphics.RenderFilmImage(slice, f.positions[i], f.Len(), opts)
+	}
+	sheet := dicomgraphics.NewFilmSheet(f.slices[0].Data, c.columns, c.rows)
+	sheet.Paper = c.paper
+
+	outPath, err := c.names.Path(f.Path, f.slices[0].Data, map[string]string{"Name": f.Name})
+	if err != nil {
+		return err
+	}
+	err = dicomgraphics.WriteFile(outPath, func(w io.Writer) error {
+		return dicomgraphics.EncodeFilmSheet(w, images, sheet)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Written", len(images), "images to", outPath)
+	return nil
+}
+
+// seriesFilms reads the files and sorts their images into series, returning a film for each.
+// If keys is set only the images selected by key object selection documents in the files are included.
+func seriesFilms(files []string, workers int, keys bool) []*film {
+	datasets := dicomgraphics.ReadFiles(files, workers, func(path string, err error) {
+		log.Println("Error reading", path+":", err)
+	})
+	var keyImages []dicomgraphics.ImageReference
+	for _, data := range datasets {
+		if data == nil {
+			continue
+		}
+		if refs, err := dicomgraphics.ParseKeyImages(data); err == nil {
+			keyImages = append(keyImages, refs...)
+		}
+	}
+	if keys && len(keyImages) == 0 {
+		log.Println("No key object selection documents found")
+		return nil
+	}
+
+	var films []*film
+	for _, src := range dicomgraphics.GroupSeriesSources(files, datasets) {
+		f := &film{SeriesSource: src}
+		for i, slice := range src.Slices {
+			if keys && !isKeyImage(keyImages, slice) {
+				continue
+			}
+			f.slices = append(f.slices, slice)
+			f.positions = append(f.positions, i)
+		}
+		if len(f.slices) > 0 {
+			films = append(films, f)
+		}
+	}
+	return films
+}
+
+func isKeyImage(refs []dicomgraphics.ImageReference, slice *dicomgraphics.Slice) bool {
+	ref := dicomgraphics.NewImageReference(slice)
+	for _, r := range refs {
+		if r.Matches(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+func main() {
+	grid := "3x4"
+	paperName, landscape := "A4", false
+	pstate := ""
+	annotate := true
+	corners := dicomgraphics.DefaultCornerLayout.String()
+	overlays := true
+	keys := false
+	outDir := ""
+	name := "{Name}"
+	workers := runtime.NumCPU()
+	flag.StringVar(&grid, "grid", grid, "The columns and rows of images on each page, such as 3x4")
+	flag.StringVar(&paperName, "paper", paperName, "The paper size: A4, A3, Letter or Legal")
+	flag.BoolVar(&landscape, "landscape", landscape, "Print on pages that are wider than they are tall")
+	flag.StringVar(&pstate, "pstate", pstate, "A presentation state file (GSPS) whose window, view and measurements "+
+		"are applied to the images")
+	flag.BoolVar(&annotate, "annotate", annotate, "Burn orientation markers and four-corner text into each image")
+	flag.StringVar(&corners, "corners", corners, "The fields of each corner for -annotate, as comma separated lists for "+
+		"top left, top right, bottom left and bottom right separated by ';'")
+	flag.BoolVar(&overlays, "overlays", overlays, "Draw the overlay planes of each image")
+	window := cli.AddWindowFlags(flag.CommandLine)
+	flag.BoolVar(&keys, "keys", keys, "Print only the images selected by the key object selection documents "+
+		"among the inputs")
+	flag.StringVar(&outDir, "out", outDir, "The directory to write to, instead of beside each input")
+	flag.StringVar(&name, "name", name, "The output file name, with fields such as {PatientID}, {SeriesNumber:04} "+
+		"or {Name} (the input file or folder name) filled in and '/' separating directories")
+	flag.IntVar(&workers, "workers", workers, "The number of files to read and series to print at once")
+	flag.Parse()
+
+	if len(flag.Args()) == 0 {
+		log.Println("Must pass a parameter - the files, directories or patterns to print")
+		return
+	}
+	switch ext := strings.ToLower(dicomgraphics.TemplateExt(name)); ext {
+	case "":
+		name += ".pdf"
+	case ".pdf":
+	default:
+		log.Println("Unsupported file type " + ext + ", the name must end in .pdf")
+		return
+	}
+
+	c := &converter{window: window, names: dicomgraphics.OutputNames{Dir: outDir, Template: name},
+		opts: dicomgraphics.FilmImageOptions{Annotate: annotate, Overlays: overlays}}
+	var err error
+	if c.columns, c.rows, err = dicomgraphics.ParseGrid(grid); err != nil {
+		log.Println(err)
+		return
+	}
+	paper, ok := dicomgraphics.FindPaperSize(paperName)
+	if !ok {
+		log.Println("Unknown paper size " + paperName)
+		return
+	}
+	c.paper = paper
+	if landscape {
+		c.paper = paper.Landscape()
+	}
+	if c.opts.Corners, err = dicomgraphics.ParseCornerLayout(corners); err != nil {
+		log.Println(err)
+		return
+	}
+	if err = window.Parse(); err != nil {
+		log.Println(err)
+		return
+	}
+	if pstate != "" {
+		if c.opts.State, err = dicomgraphics.LoadPresentationState(pstate); err != nil {
+			log.Println("Error reading presentation state "+pstate+":", err)
+			return
+		}
+		// a window from the command line replaces that of the files and of the presentation state
+		if window.Set() {
+			c.opts.State.Windows = nil
+		}
+	}
+
+	// check the template fields before reading any files
+	if _, err = dicomgraphics.ExpandName(name, &dicom.Dataset{}, map[string]string{"Name": ""}); err != nil {
+		log.Println(err)
+		return
+	}
+
+	files := dicomgraphics.ListFiles(flag.Args(), func(path string, err error) {
+		log.Println("Error reading", path+":", err)
+	})
+	films := seriesFilms(files, workers, keys)
+	dicomgraphics.Parallel(workers, len(films), func(i int) {
+		if err := c.convert(films[i]); err != nil {
+			log.Println("Error converting", films[i].Path+":", err)
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"golang.org/x/term"
+
+	"github.com/fynelabs/dicomgraphics"
+	"github.com/fynelabs/dicomgraphics/internal/cli"
+)
+
+// terminalColumns returns the width of the terminal that output is written to, or of the COLUMNS variable of the
+// shell if output is redirected, or 80 if neither is known.
+func terminalColumns() int {
+	if cols, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && cols > 0 {
+		return cols
+	}
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols
+	}
+	return 80
+}
+
+// printer holds the options that are applied to each file and frame.
+type printer struct {
+	format              string
+	frames              string
+	window              *cli.WindowFlags
+	overlays            bool
+	columns, rows       int
+	maxWidth, maxHeight int
+	caption             bool
+}
+
+// printFile writes the selected frames of a file to the terminal, returning the first error.
+func (p *printer) printFile(path string) error {
+	data, err := dicom.ParseFile(path, nil)
+	if err != nil {
+		return err
+	}
+
+	series := dicomgraphics.NewSeries(&data)
+	if series.Len() == 0 {
+		return errors.New("no image found")
+	}
+	selected, err := dicomgraphics.ParseFrames(p.frames, series.Len())
+	if err != nil {
+		return err
+	}
+
+	for _, index := range selected {
+		img, level, width := p.render(series.Slices[index], index, series.Len())
+		if err = p.write(img); err != nil {
+			return err
+		}
+		if !p.caption {
+			continue
+		}
+
+		caption := filepath.Base(path)
+		if series.Len() > 1 {
+			caption += fmt.Sprint(" frame ", index+1, " of ", series.Len())
+		}
+		fmt.Println(caption, "at", level, "width", width)
+	}
+	return nil
+}
+
+// render draws a frame with the window options, returning the image and the window it was drawn at.
+func (p *printer) render(slice *dicomgraphics.Slice, index, count int) (image.Image, int16, int16) {
+	level, width := p.window.Window(slice.Data)
+	opts := dicomgraphics.FilmImageOptions{Level: level, Width: width, Overlays: p.overlays}
+	return dicomgraphics.RenderFilmImage(slice, index, count, opts), level, width
+}
+
+// write scales an image to the terminal and prints it in the chosen format.
+func (p *printer) write(img image.Image) error {
+	switch p.format {
+	case "sixel":
+		out, _ := dicomgraphics.ScaleToFit(img, p.maxWidth, p.maxHeight)
+		if err := dicomgraphics.EncodeSixel(os.Stdout, out); err != nil {
+			return err
+		}
+		fmt.Println()
+		return nil
+	case "kitty":
+		out, _ := dicomgraphics.ScaleToFit(img, p.maxWidth, p.maxHeight)
+		return dicomgraphics.EncodeKitty(os.Stdout, out, p.columns, p.rows)
+	}
+
+	// each character is two pixels high, which is about as tall as it is wide
+	columns := p.columns
+	if columns < 1 {
+		columns = terminalColumns()
+	}
+	out, _ := dicomgraphics.ScaleToFit(img, columns, p.rows*2)
+	return dicomgraphics.EncodeHalfBlocks(os.Stdout, out)
+}
+
+func main() {
+	format := "ansi"
+	frames := "1"
+	overlays := true
+	columns, rows := 0, 0
+	maxWidth, maxHeight := 0, 0
+	caption := true
+	flag.StringVar(&format, "format", format, "The output: ansi (coloured half blocks, for any 24 bit colour terminal), "+
+		"sixel or kitty (the graphics protocol of Kitty, WezTerm and Ghostty)")
+	flag.StringVar(&frames, "frames", frames, "The frames to show, as all, a frame number such as 3 or a range such as 1-10")
+	window := cli.AddWindowFlags(flag.CommandLine)
+	flag.BoolVar(&overlays, "overlays", overlays, "Draw the overlay planes of the image")
+	flag.IntVar(&columns, "cols", columns, "The width in characters, by default the width of the terminal for ansi "+
+		"and the size of the image for kitty")
+	flag.IntVar(&rows, "rows", rows, "The height in lines, by default as tall as the width requires")
+	flag.IntVar(&maxWidth, "max-width", maxWidth, "The largest width in pixels for sixel and kitty, "+
+		"larger images are scaled down")
+	flag.IntVar(&maxHeight, "max-height", maxHeight, "The largest height in pixels for sixel and kitty, "+
+		"larger images are scaled down")
+	flag.BoolVar(&caption, "caption", caption, "Print the file name, frame and window under each image")
+	flag.Parse()
+
+	if len(flag.Args()) == 0 {
+		log.Println("Must pass a parameter - the files, directories or patterns to show")
+		return
+	}
+	format = strings.ToLower(format)
+	if format != "ansi" && format != "sixel" && format != "kitty" {
+		log.Println("Unknown format " + format + ", must be ansi, sixel or kitty")
+		return
+	}
+
+	p := &printer{format: format, frames: frames, window: window, overlays: overlays, columns: columns, rows: rows,
+		maxWidth: maxWidth, maxHeight: maxHeight, caption: caption}
+	if err := window.Parse(); err != nil {
+		log.Println(err)
+		return
+	}
+
+	// files are shown one after another, so they are read in order rather than at once
+	files := dicomgraphics.ListFiles(flag.Args(), func(path string, err error) {
+		log.Println("Error reading", path+":", err)
+	})
+	for _, path := range files {
+		if err := p.printFile(path); err != nil {
+			log.Println("Error showing", path+":", err)
+		}
+	}
+}
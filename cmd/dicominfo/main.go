@@ -1,53 +1,211 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
 
 	"github.com/suyashkumar/dicom"
 	"github.com/suyashkumar/dicom/pkg/tag"
+
+	"github.com/fynelabs/dicomgraphics"
 )
 
-type info struct {
-	studyDate, name, id string
+const (
+	formatCSV  = "csv"
+	formatJSON = "json"
+
+	groupInstance = "instance"
+	groupSeries   = "series"
+)
+
+// parseTags reads a comma separated list of tag keywords or numbers, returning the tags and their column names.
+func parseTags(list string) ([]tag.Tag, []string, error) {
+	var tags []tag.Tag
+	var names []string
+	// commas inside brackets separate the group and element of a tag rather than the tags
+	depth := 0
+	fields := strings.FieldsFunc(list, func(r rune) bool {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		return r == ',' && depth == 0
+	})
+	for _, name := range fields {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		t, err := dicomgraphics.ParseTag(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		tags = append(tags, t)
+		names = append(names, name)
+	}
+	return tags, names, nil
+}
+
+// tagValues returns the value of each tag in a dataset, with multiple values separated by `\`.
+func tagValues(data *dicom.Dataset, tags []tag.Tag) []string {
+	values := make([]string, len(tags))
+	for i, t := range tags {
+		values[i] = strings.TrimSpace(dicomgraphics.TagString(data, t))
+	}
+	return values
+}
+
+func writeJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// scanResult is an instance, or the first instance of a series, in the JSON output of a scan.
+type scanResult struct {
+	Path string
+	// Instances is the number of files in a series, when grouping by series.
+	Instances int `json:",omitempty"`
+	Dataset   dicomgraphics.JSONDataset
+}
+
+// writeFile prints the information of a single file, without the path or count columns of a scan.
+func writeFile(data *dicom.Dataset, tags []tag.Tag, names []string, dump bool, format string, header bool) error {
+	switch {
+	case dump && format == formatJSON:
+		return writeJSON(dicomgraphics.NewJSONDataset(data))
+	case dump:
+		_, err := fmt.Print(dicomgraphics.DumpTags(dicomgraphics.TagTree(data)))
+		return err
+	case format == formatJSON:
+		return writeJSON(dicomgraphics.NewJSONDataset(data, tags...))
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	if header {
+		_ = w.Write(names)
+	}
+	_ = w.Write(tagValues(data, tags))
+	w.Flush()
+	return w.Error()
 }
 
-func (i *info) loadInfo(data dicom.Dataset) {
-	for _, elem := range data.Elements {
-		if elem.Tag == tag.PatientName {
-			i.name = fmt.Sprintf("%v", elem.Value)
-		} else if elem.Tag == tag.PatientID {
-			i.id = fmt.Sprintf("%v", elem.Value)
-		} else if elem.Tag == tag.StudyDate {
-			i.studyDate = fmt.Sprintf("%v", elem.Value)
+// writeScan prints a row for each instance, or each series if counts are set, with its path as the first column.
+func writeScan(instances []instance, counts []int, tags []tag.Tag, names []string, dump bool, format string,
+	header bool) error {
+	if dump && format == formatCSV {
+		for _, inst := range instances {
+			fmt.Println("# " + inst.path)
+			fmt.Print(dicomgraphics.DumpTags(dicomgraphics.TagTree(inst.data)))
+		}
+		return nil
+	}
+
+	if !dump && format == formatCSV {
+		w := csv.NewWriter(os.Stdout)
+		if header {
+			columns := append([]string{"Path"}, names...)
+			if counts != nil {
+				columns = append(columns, "Instances")
+			}
+			_ = w.Write(columns)
+		}
+		for i, inst := range instances {
+			row := append([]string{inst.path}, tagValues(inst.data, tags)...)
+			if counts != nil {
+				row = append(row, strconv.Itoa(counts[i]))
+			}
+			_ = w.Write(row)
 		}
+		w.Flush()
+		return w.Error()
 	}
 
+	results := make([]scanResult, len(instances))
+	for i, inst := range instances {
+		results[i] = scanResult{Path: inst.path}
+		if dump {
+			results[i].Dataset = dicomgraphics.NewJSONDataset(inst.data)
+		} else {
+			results[i].Dataset = dicomgraphics.NewJSONDataset(inst.data, tags...)
+		}
+		if counts != nil {
+			results[i].Instances = counts[i]
+		}
+	}
+	return writeJSON(results)
 }
 
 func main() {
 	showHeader := true
+	tagList := "PatientID,PatientName,StudyDate"
+	dump := false
+	format := formatCSV
+	group := groupInstance
+	workers := runtime.NumCPU()
 	flag.BoolVar(&showHeader, "header", false, "Show header information")
+	flag.StringVar(&tagList, "tags", tagList, "The tags to print, as a comma separated list of keywords or (gggg,eeee)")
+	flag.BoolVar(&dump, "dump", dump, "Print every element of the dataset, including nested sequences")
+	flag.StringVar(&format, "format", format, "The output format, csv or json (the DICOM JSON Model of PS3.18)")
+	flag.StringVar(&group, "group", group, "When scanning directories print a row per instance or per series")
+	flag.IntVar(&workers, "workers", workers, "The number of files to read at once when scanning directories")
 	flag.Parse()
 
-	if len(flag.Args()) != 1 {
-		log.Println("Must pass a parameter - the file to extract information")
+	if len(flag.Args()) == 0 {
+		log.Println("Must pass a parameter - the files, directories or patterns to extract information")
 		return
 	}
-
-	path := flag.Arg(0)
-	data, err := dicom.ParseFile(path, nil)
+	if format != formatCSV && format != formatJSON {
+		log.Println("Unknown format " + format + ", must be csv or json")
+		return
+	}
+	if group != groupInstance && group != groupSeries {
+		log.Println("Unknown group " + group + ", must be instance or series")
+		return
+	}
+	tags, names, err := parseTags(tagList)
 	if err != nil {
-		log.Println("Error parsing " + path)
+		log.Println(err)
 		return
 	}
 
-	info := &info{}
-	info.loadInfo(data)
+	if info, err := os.Stat(flag.Arg(0)); flag.NArg() == 1 && err == nil && !info.IsDir() {
+		path := flag.Arg(0)
+		data, err := dicom.ParseFile(path, nil)
+		if err != nil {
+			log.Println("Error parsing " + path)
+			return
+		}
+
+		if err = writeFile(&data, tags, names, dump, format, showHeader); err != nil {
+			log.Println("Error writing output:", err)
+		}
+		return
+	}
 
-	if showHeader {
-		fmt.Printf("PatientId,PatientName,StudyDate\n")
+	files := dicomgraphics.ListFiles(flag.Args(), func(path string, err error) {
+		log.Println("Error reading", path+":", err)
+	})
+	instances := scan(files, workers)
+	var counts []int
+	if group == groupSeries {
+		series := seriesOf(instances)
+		instances, counts = make([]instance, len(series)), make([]int, len(series))
+		for i, s := range series {
+			instances[i], counts[i] = s.instance, s.count
+		}
+	}
+	if err = writeScan(instances, counts, tags, names, dump, format, showHeader); err != nil {
+		log.Println("Error writing output:", err)
 	}
-	fmt.Printf("%s,%s,%s\n", info.id, info.name, info.studyDate)
 }
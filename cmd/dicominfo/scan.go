@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+
+	"github.com/fynelabs/dicomgraphics"
+)
+
+// instance is a file read by the scanner.
+type instance struct {
+	path string
+	data *dicom.Dataset
+}
+
+// scan parses files, other than their pixel data, using a pool of workers, returning the instances in path order.
+// Files that are not DICOM are reported on stderr and left out.
+func scan(files []string, workers int) []instance {
+	var mu sync.Mutex
+	var instances []instance
+	dicomgraphics.Parallel(workers, len(files), func(i int) {
+		data, err := dicom.ParseFile(files[i], nil, dicom.SkipPixelData())
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			log.Println("Error parsing", files[i]+":", err)
+			return
+		}
+		instances = append(instances, instance{path: files[i], data: &data})
+	})
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].path < instances[j].path
+	})
+	return instances
+}
+
+// series is a group of instances sharing a Series Instance UID, described by the first of them.
+type series struct {
+	instance
+	count int
+}
+
+// seriesOf collapses instances into one entry per series, keeping the order the series are first seen.
+func seriesOf(instances []instance) []*series {
+	var ret []*series
+	byUID := make(map[string]*series)
+	for _, inst := range instances {
+		uid := tagValues(inst.data, []tag.Tag{tag.SeriesInstanceUID})[0]
+		if s, ok := byUID[uid]; ok && uid != "" {
+			s.count++
+			continue
+		}
+
+		s := &series{instance: inst, count: 1}
+		byUID[uid] = s
+		ret = append(ret, s)
+	}
+	return ret
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+
+	"github.com/fynelabs/dicomgraphics"
+	"github.com/fynelabs/dicomgraphics/internal/cli"
+)
+
+// converter holds the options that are applied to each montage.
+type converter struct {
+	opts    dicomgraphics.MontageOptions
+	window  *cli.WindowFlags
+	quality int
+	names   dicomgraphics.OutputNames
+}
+
+// convert draws a montage and writes it as a png or jpg, depending on the extension of its name.
+func (c *converter) convert(src *dicomgraphics.SeriesSource) error {
+	if src.Len() == 0 {
+		return errors.New("no images found")
+	}
+
+	// a window from the command line replaces that of the files
+	opts := c.opts
+	opts.Level, opts.Width = c.window.Window(src.Slices[0].Data)
+	img := dicomgraphics.RenderMontage(src.Slices, opts)
+
+	outPath, err := c.names.Path(src.Path, src.Slices[0].Data, map[string]string{"Name": src.Name})
+	if err != nil {
+		return err
+	}
+	err = dicomgraphics.WriteFile(outPath, func(w io.Writer) error {
+		if strings.EqualFold(filepath.Ext(outPath), ".png") {
+			return png.Encode(w, img)
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: c.quality})
+	})
+	if err != nil {
+		return err
+	}
+
+	shown := (src.Len() + opts.Step - 1) / opts.Step
+	fmt.Println("Written", shown, "of", src.Len(), "images to", outPath, "at", opts.Level, "width", opts.Width)
+	return nil
+}
+
+func main() {
+	columns, step, tileSize := 0, 1, 256
+	labels := dicomgraphics.CornerImage
+	header := true
+	overlays := true
+	overlayColor := "#ffffff"
+	quality := jpeg.DefaultQuality
+	outDir := ""
+	name := "{Name}_montage"
+	workers := runtime.NumCPU()
+	flag.IntVar(&columns, "columns", columns, "The number of tiles in each row, 0 chooses a square grid")
+	flag.IntVar(&step, "step", step, "Show every Nth image of each series, starting with the first")
+	flag.IntVar(&tileSize, "tile-size", tileSize, "The largest width or height of each tile, 0 keeps the image size")
+	window := cli.AddWindowFlags(flag.CommandLine)
+	flag.StringVar(&labels, "labels", labels, "The fields drawn on each tile, as a comma separated list "+
+		"such as image,location, or none")
+	flag.BoolVar(&header, "header", header, "Draw the patient, study and series details above the tiles")
+	flag.BoolVar(&overlays, "overlays", overlays, "Draw the overlay planes of each image")
+	flag.StringVar(&overlayColor, "overlay-color", overlayColor, "The colour of overlay planes, as #rrggbb")
+	flag.IntVar(&quality, "quality", quality, "The JPEG quality, from 1 to 100")
+	flag.StringVar(&outDir, "out", outDir, "The directory to write to, instead of beside each input")
+	flag.StringVar(&name, "name", name, "The output file name, with fields such as {PatientID}, {SeriesNumber:04} "+
+		"or {Name} (the input file or folder name) filled in and '/' separating directories")
+	flag.IntVar(&workers, "workers", workers, "The number of files to read and series to draw at once")
+	flag.Parse()
+
+	if len(flag.Args()) == 0 {
+		log.Println("Must pass a parameter - the files, directories or patterns to draw")
+		return
+	}
+	if columns < 0 || step < 1 || tileSize < 0 {
+		log.Println("Columns and tile size must not be negative, and step must be at least 1")
+		return
+	}
+	if quality < 1 || quality > 100 {
+		log.Println("Quality must be from 1 to 100")
+		return
+	}
+	switch ext := strings.ToLower(dicomgraphics.TemplateExt(name)); ext {
+	case "":
+		name += ".png"
+	case ".png", ".jpg", ".jpeg":
+	default:
+		log.Println("Unsupported file type " + ext + ", the name must end in .png or .jpg")
+		return
+	}
+
+	c := &converter{window: window, quality: quality, names: dicomgraphics.OutputNames{Dir: outDir, Template: name},
+		opts: dicomgraphics.MontageOptions{Columns: columns, Step: step, TileSize: tileSize, Header: header,
+			Overlays: overlays, Labels: []string{}}}
+	if err := window.Parse(); err != nil {
+		log.Println(err)
+		return
+	}
+
+	var err error
+	if labels != "none" {
+		if c.opts.Labels, err = dicomgraphics.ParseCornerFields(labels); err != nil {
+			log.Println(err)
+			return
+		}
+	}
+	if c.opts.OverlayColor, err = dicomgraphics.ParseColor(overlayColor); err != nil {
+		log.Println("Invalid overlay colour " + overlayColor)
+		return
+	}
+
+	// check the template fields before reading any files
+	if _, err = dicomgraphics.ExpandName(name, &dicom.Dataset{}, map[string]string{"Name": ""}); err != nil {
+		log.Println(err)
+		return
+	}
+
+	files := dicomgraphics.ListFiles(flag.Args(), func(path string, err error) {
+		log.Println("Error reading", path+":", err)
+	})
+	dicomgraphics.ConvertSeries(files, workers, true, c.convert, func(path string, err error) {
+		log.Println("Error converting", path+":", err)
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/fynelabs/dicomgraphics"
+	"github.com/suyashkumar/dicom"
+)
+
+var shapes = map[string]dicomgraphics.MeasurementKind{
+	"rectangle": dicomgraphics.MeasureRectangle,
+	"ellipse":   dicomgraphics.MeasureEllipse,
+	"polygon":   dicomgraphics.MeasureFreehand,
+}
+
+func parsePoints(list string) ([]dicomgraphics.Point, error) {
+	var points []dicomgraphics.Point
+	for _, pair := range strings.Fields(list) {
+		coords := strings.Split(pair, ",")
+		if len(coords) != 2 {
+			return nil, fmt.Errorf("point %q should be in the form x,y", pair)
+		}
+
+		x, err := strconv.ParseFloat(coords[0], 64)
+		if err != nil {
+			return nil, err
+		}
+		y, err := strconv.ParseFloat(coords[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, dicomgraphics.Point{X: x, Y: y})
+	}
+
+	return points, nil
+}
+
+func main() {
+	shape := "ellipse"
+	list := ""
+	frame := 1
+	showHeader := false
+	flag.StringVar(&shape, "shape", shape, "Region shape: rectangle, ellipse or polygon")
+	flag.StringVar(&list, "points", list, "Space separated x,y image coordinates, two corners or the polygon vertices")
+	flag.IntVar(&frame, "frame", frame, "The frame to measure, starting at 1")
+	flag.BoolVar(&showHeader, "header", false, "Show header information")
+	flag.Parse()
+
+	if len(flag.Args()) != 1 {
+		log.Println("Must pass a parameter - the file to measure")
+		return
+	}
+	kind, ok := shapes[shape]
+	if !ok {
+		log.Println("Unknown shape " + shape)
+		return
+	}
+	points, err := parsePoints(list)
+	if err != nil {
+		log.Println("Invalid points:", err)
+		return
+	}
+	m := &dicomgraphics.Measurement{Kind: kind, Points: points}
+	if !m.Complete() {
+		log.Println("Not enough points for a", shape)
+		return
+	}
+
+	path := flag.Arg(0)
+	data, err := dicom.ParseFile(path, nil)
+	if err != nil {
+		log.Println("Error parsing " + path)
+		return
+	}
+
+	series := dicomgraphics.NewSeries(&data)
+	if frame < 1 || frame > series.Len() {
+		log.Println("Frame", frame, "not found, the file has", series.Len())
+		return
+	}
+	img := dicomgraphics.NewDICOMImage(series.Slices[frame-1].Frame, 0, 1)
+	img.SetBitsStored(dicomgraphics.BitsStored(&data))
+	img.SetRescale(dicomgraphics.Rescale(&data))
+
+	cal := dicomgraphics.PixelCalibration(&data)
+	stats, _ := img.RegionStatistics(m, cal)
+
+	if showHeader {
+		fmt.Printf("Mean,StdDev,Min,Max,Count,Area,Units,AreaUnits\n")
+	}
+	fmt.Printf("%g,%g,%g,%g,%d,%g,%s,%s\n", stats.Mean, stats.StdDev, stats.Min, stats.Max, stats.Count,
+		stats.Area, dicomgraphics.ModalityUnits(&data), cal.AreaUnits())
+}
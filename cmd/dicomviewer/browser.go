@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/fynelabs/dicomgraphics"
+)
+
+const thumbnailSize = 96
+
+// thumbnail shows a series in the browser, tapping it or dragging it onto a viewport loads the series there.
+type thumbnail struct {
+	widget.BaseWidget
+	series  *dicomgraphics.StudySeries
+	dragPos fyne.Position
+
+	v *viewer
+}
+
+func newThumbnail(v *viewer, series *dicomgraphics.StudySeries) *thumbnail {
+	t := &thumbnail{series: series, v: v}
+	t.ExtendBaseWidget(t)
+	return t
+}
+
+func (t *thumbnail) CreateRenderer() fyne.WidgetRenderer {
+	// the middle slice is usually more representative than the first
+	slice := t.series.Slices[t.series.Len()/2]
+	img := canvas.NewImageFromImage(dicomgraphics.Thumbnail(slice, thumbnailSize))
+	img.FillMode = canvas.ImageFillContain
+	img.SetMinSize(fyne.NewSize(thumbnailSize, thumbnailSize))
+
+	desc := t.series.Description
+	if desc == "" {
+		desc = fmt.Sprintf("Series %d", t.series.Number)
+	}
+	title := widget.NewLabel(desc)
+	title.Truncation = fyne.TextTruncateEllipsis
+	info := canvas.NewText(fmt.Sprintf("%s  %d images", t.series.Modality, t.series.Len()), theme.ForegroundColor())
+	info.TextSize = theme.CaptionTextSize()
+	return widget.NewSimpleRenderer(container.NewVBox(img, title, info))
+}
+
+func (t *thumbnail) Tapped(_ *fyne.PointEvent) {
+	t.v.startEvent()
+	defer t.v.endEvent()
+
+	t.v.showSeries(t.v.active, t.series)
+}
+
+func (t *thumbnail) Dragged(ev *fyne.DragEvent) {
+	t.dragPos = ev.AbsolutePosition
+}
+
+func (t *thumbnail) DragEnd() {
+	t.v.startEvent()
+	defer t.v.endEvent()
+
+	if vp := t.v.viewportAt(t.dragPos); vp != nil {
+		t.v.setActive(vp)
+		t.v.showSeries(vp, t.series)
+	}
+}
+
+// setStudies lists the series of the studies in the browser, which is hidden if there is only a single series.
+func (v *viewer) setStudies(studies []*dicomgraphics.Study) {
+	count := 0
+	var cards []fyne.CanvasObject
+	for _, study := range studies {
+		var thumbs []fyne.CanvasObject
+		for _, series := range study.Series {
+			if series.Len() > 0 {
+				thumbs = append(thumbs, newThumbnail(v, series))
+			}
+		}
+		count += len(thumbs)
+
+		title := study.Description
+		if title == "" {
+			title = "Study"
+		}
+		cards = append(cards, widget.NewCard(title, study.Date+" "+study.PatientName, container.NewVBox(thumbs...)))
+	}
+
+	v.browser.Objects = cards
+	v.browser.Refresh()
+	if count > 1 {
+		v.browserScroll.Show()
+	} else {
+		v.browserScroll.Hide()
+	}
+}
+
+// showSeries loads a series from the browser into a viewport.
+func (v *viewer) showSeries(vp *viewport, series *dicomgraphics.StudySeries) {
+	vp.loadSeries(series.Series, series.Slices[0].Data)
+	if vp == v.active {
+		v.refreshActive()
+	}
+}
+
+// viewportAt returns the visible viewport at an absolute position, or nil if there is none.
+func (v *viewer) viewportAt(pos fyne.Position) *viewport {
+	driver := fyne.CurrentApp().Driver()
+	for _, vp := range v.visibleViewports() {
+		topLeft := driver.AbsolutePositionForObject(vp)
+		size := vp.Size()
+		if pos.X >= topLeft.X && pos.Y >= topLeft.Y && pos.X < topLeft.X+size.Width && pos.Y < topLeft.Y+size.Height {
+			return vp
+		}
+	}
+	return nil
+}
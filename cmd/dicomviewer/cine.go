@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	cineLoop   = "Loop"
+	cineBounce = "Bounce"
+
+	maxFrameRate = 120
+)
+
+// player steps the active viewport through its slices at the chosen frame rate.
+type player struct {
+	mode      string
+	direction int
+	stop      chan struct{}
+
+	play *widget.Button
+	fps  *widget.Slider
+	rate *widget.Label
+
+	v *viewer
+}
+
+func newPlayer(v *viewer) *player {
+	p := &player{mode: cineLoop, direction: 1, v: v}
+	p.play = widget.NewButtonWithIcon("", theme.MediaPlayIcon(), v.handle(p.toggle))
+	p.rate = widget.NewLabel("")
+	p.fps = widget.NewSlider(1, maxFrameRate)
+	p.fps.OnChanged = func(fps float64) {
+		p.v.startEvent()
+		defer p.v.endEvent()
+
+		p.v.active.fps = fps
+		p.rate.SetText(fmt.Sprintf("%.0f fps", fps))
+	}
+	return p
+}
+
+func (p *player) makeUI() fyne.CanvasObject {
+	modes := widget.NewRadioGroup([]string{cineLoop, cineBounce}, func(mode string) {
+		p.v.startEvent()
+		defer p.v.endEvent()
+
+		if mode == "" {
+			return
+		}
+		p.mode = mode
+		p.direction = 1
+	})
+	modes.Horizontal = true
+	modes.Required = true
+	modes.SetSelected(cineLoop)
+
+	return widget.NewCard("Cine", "", container.NewVBox(
+		container.NewBorder(nil, nil, p.play, p.rate, p.fps), modes))
+}
+
+// refresh shows the frame rate of the active viewport.
+func (p *player) refresh() {
+	fps := p.v.active.fps
+	if fps > maxFrameRate {
+		fps = maxFrameRate
+	}
+	p.fps.SetValue(fps)
+}
+
+// playing returns true if the player is stepping through the frames.
+func (p *player) playing() bool {
+	return p.stop != nil
+}
+
+func (p *player) toggle() {
+	if p.stop == nil {
+		p.start()
+	} else {
+		p.pause()
+	}
+}
+
+func (p *player) start() {
+	if p.stop != nil {
+		return
+	}
+
+	p.stop = make(chan struct{})
+	p.play.SetIcon(theme.MediaPauseIcon())
+	go p.run(p.stop)
+}
+
+func (p *player) pause() {
+	if p.stop == nil {
+		return
+	}
+
+	close(p.stop)
+	p.stop = nil
+	p.play.SetIcon(theme.MediaPlayIcon())
+	p.v.refreshTags()
+}
+
+// run ticks at the frame rate of the active viewport until stop is closed.
+// The frame rate is read, and each frame stepped, holding the viewer lock so that they do not change during an event.
+func (p *player) run(stop chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(p.interval()):
+			p.tick(stop)
+		}
+	}
+}
+
+// interval returns the time to show each frame for.
+func (p *player) interval() time.Duration {
+	p.v.lock.Lock()
+	defer p.v.lock.Unlock()
+
+	return time.Duration(float64(time.Second) / p.v.active.fps)
+}
+
+// tick steps to the next frame, unless the player was paused whilst waiting for the lock.
+func (p *player) tick(stop chan struct{}) {
+	p.v.lock.Lock()
+	defer p.v.lock.Unlock()
+
+	select {
+	case <-stop:
+	default:
+		p.step()
+	}
+}
+
+func (p *player) step() {
+	vp := p.v.active
+	count := vp.series.Len()
+	if count < 2 {
+		return
+	}
+
+	next := vp.currentFrame + p.direction
+	if p.mode == cineBounce && (next < 0 || next >= count) {
+		p.direction = -p.direction
+		next = vp.currentFrame + p.direction
+	}
+	p.v.setFrame(next)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"image/color"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/fynelabs/dicomgraphics"
+)
+
+const cornerLayoutSetting = "cornerLayout"
+
+var cornerNames = []string{"Top left", "Top right", "Bottom left", "Bottom right"}
+
+// loadCornerLayout reads the saved four-corner text fields, or the default layout if none were saved.
+func (v *viewer) loadCornerLayout(prefs fyne.Preferences) {
+	v.corners = dicomgraphics.DefaultCornerLayout
+	saved := prefs.StringWithFallback(cornerLayoutSetting, v.corners.String())
+	if layout, err := dicomgraphics.ParseCornerLayout(saved); err == nil {
+		v.corners = layout
+	}
+}
+
+// editCorners shows a form to choose the fields shown in each corner, which is remembered for next time.
+func (v *viewer) editCorners() {
+	entries := make([]*widget.Entry, len(v.corners))
+	items := make([]*widget.FormItem, len(v.corners))
+	for i, fields := range v.corners {
+		entries[i] = widget.NewEntry()
+		entries[i].SetText(strings.Join(fields, ","))
+		items[i] = widget.NewFormItem(cornerNames[i], entries[i])
+	}
+	items[0].HintText = "patient, id, date, study, series, image, location, window, zoom"
+
+	dialog.ShowForm("Corner text", "Apply", "Cancel", items, func(ok bool) {
+		v.startEvent()
+		defer v.endEvent()
+
+		if !ok {
+			return
+		}
+
+		corners := make([]string, len(entries))
+		for i, e := range entries {
+			corners[i] = e.Text
+		}
+		layout, err := dicomgraphics.ParseCornerLayout(strings.Join(corners, ";"))
+		if err != nil {
+			dialog.ShowError(err, v.win)
+			return
+		}
+
+		v.corners = layout
+		fyne.CurrentApp().Preferences().SetString(cornerLayoutSetting, layout.String())
+		v.setShowCorners(v.showCorners)
+	}, v.win)
+}
+
+// cornerObjects draws the four-corner text and orientation markers of the current slice over the image.
+func (vp *viewport) cornerObjects() []fyne.CanvasObject {
+	slice := vp.currentSlice()
+	if slice == nil {
+		return nil
+	}
+
+	size := vp.Size()
+	_, scale := vp.imageTransform(size)
+	info := dicomgraphics.CornerInfo{Data: slice.Data, Plane: slice.Plane, Index: vp.currentFrame, Count: vp.series.Len(),
+		Level: vp.dicom.WindowLevel(), Width: vp.dicom.WindowWidth(), Zoom: float64(scale)}
+
+	var objs []fyne.CanvasObject
+	text := func(s string, pos func(fyne.Size) fyne.Position) {
+		if s == "" {
+			return
+		}
+
+		t := canvas.NewText(s, color.White)
+		t.TextSize = theme.CaptionTextSize()
+		t.Move(pos(t.MinSize()))
+		t.Resize(t.MinSize())
+		objs = append(objs, t)
+	}
+	for i, lines := range vp.parent.corners.Text(info) {
+		right := i == dicomgraphics.TopRight || i == dicomgraphics.BottomRight
+		bottom := i == dicomgraphics.BottomLeft || i == dicomgraphics.BottomRight
+		for j, line := range lines {
+			row := j
+			if bottom {
+				row = len(lines) - 1 - j
+			}
+			text(line, func(min fyne.Size) fyne.Position {
+				pos := fyne.NewPos(dicomgraphics.CornerMargin, dicomgraphics.CornerMargin+float32(row)*min.Height)
+				if right {
+					pos.X = size.Width - dicomgraphics.CornerMargin - min.Width
+				}
+				if bottom {
+					pos.Y = size.Height - dicomgraphics.CornerMargin - float32(row+1)*min.Height
+				}
+				return pos
+			})
+		}
+	}
+
+	o, ok := dicomgraphics.ImageOrientation(slice.Data, slice.Plane)
+	if !ok {
+		return objs
+	}
+	o = o.Transform(vp.transform.Flip, vp.transform.Rotation)
+	text(o.Left, func(min fyne.Size) fyne.Position {
+		return fyne.NewPos(dicomgraphics.CornerMargin, (size.Height-min.Height)/2)
+	})
+	text(o.Right, func(min fyne.Size) fyne.Position {
+		return fyne.NewPos(size.Width-dicomgraphics.CornerMargin-min.Width, (size.Height-min.Height)/2)
+	})
+	text(o.Top, func(min fyne.Size) fyne.Position {
+		return fyne.NewPos((size.Width-min.Width)/2, dicomgraphics.CornerMargin)
+	})
+	text(o.Bottom, func(min fyne.Size) fyne.Position {
+		return fyne.NewPos((size.Width-min.Width)/2, size.Height-dicomgraphics.CornerMargin-min.Height)
+	})
+	return objs
+}
+
+// scaleBarObjects draws a labelled scale bar at the bottom of the viewport, sized for the current zoom.
+func (vp *viewport) scaleBarObjects() []fyne.CanvasObject {
+	cal := vp.calibration()
+	if !cal.Valid() {
+		return nil
+	}
+
+	size := vp.Size()
+	_, scale := vp.imageTransform(size)
+	spacing := cal.ColumnSpacing
+	if vp.transform.Swapped() {
+		spacing = cal.RowSpacing
+	}
+	length, label, ok := dicomgraphics.ScaleBar(spacing/float64(scale), float64(size.Width)*dicomgraphics.ScaleBarFraction)
+	if !ok {
+		return nil
+	}
+
+	text := canvas.NewText(label, color.White)
+	text.TextSize = theme.CaptionTextSize()
+	textSize := text.MinSize()
+	y := size.Height - dicomgraphics.CornerMargin - textSize.Height - dicomgraphics.ScaleBarTick
+	left := (size.Width - float32(length)) / 2
+	right := left + float32(length)
+	text.Move(fyne.NewPos((size.Width-textSize.Width)/2, y-dicomgraphics.ScaleBarTick-textSize.Height))
+	text.Resize(textSize)
+
+	objs := []fyne.CanvasObject{text}
+	line := func(from, to fyne.Position) {
+		l := canvas.NewLine(color.White)
+		l.StrokeWidth = 1.5
+		l.Position1, l.Position2 = from, to
+		objs = append(objs, l)
+	}
+	line(fyne.NewPos(left, y), fyne.NewPos(right, y))
+	line(fyne.NewPos(left, y-dicomgraphics.ScaleBarTick), fyne.NewPos(left, y))
+	line(fyne.NewPos(right, y-dicomgraphics.ScaleBarTick), fyne.NewPos(right, y))
+	return objs
+}
@@ -7,116 +7,173 @@ import (
 	"io"
 	"log"
 	"os"
-	"strconv"
+	"sync"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
-	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/widget"
 
 	"github.com/fynelabs/dicomgraphics"
 	"github.com/suyashkumar/dicom"
-	"github.com/suyashkumar/dicom/pkg/frame"
-	"github.com/suyashkumar/dicom/pkg/tag"
 )
 
 type viewer struct {
-	dicom                  *dicomgraphics.DICOMImage
-	frames                 []*frame.Frame
-	currentFrame           int
-	image                  *canvas.Image
-	study, name, id, frame *widget.Label
+	viewports              []*viewport
+	active                 *viewport
+	grid                   *fyne.Container
+	syncScroll, syncWindow bool
+	frame                  *widget.Label
+	status                 *widget.Label
 	level, width           *widget.Entry
+	layout                 *widget.Select
+	tools                  map[desktop.MouseButton]mouseTool
+	ctrlDown               bool
+	showOverlays           bool
+	overlays               *widget.Check
+	browser                *fyne.Container
+	browserScroll          *container.Scroll
+	tags                   *tagBrowser
+	showCorners            bool
+	cornerText             *widget.Check
+	corners                dicomgraphics.CornerLayout
+	showScaleBar           bool
+	scaleBar               *widget.Check
+	player                 *player
+
+	// lock is held while an event is handled and while the cine player steps a frame,
+	// so that the player goroutine never changes the viewports during an event.
+	lock sync.Mutex
+	// events counts the events being handled, which may start others such as checking a box.
+	events int
 
 	win fyne.Window
 }
 
+// startEvent holds the viewer lock while an event is handled, unless an event it was started from already holds it.
+// Events are delivered one at a time, so only the cine player competes for the lock.
+func (v *viewer) startEvent() {
+	if v.events == 0 {
+		v.lock.Lock()
+	}
+	v.events++
+}
+
+// endEvent releases the lock held by startEvent once the outermost event is finished.
+func (v *viewer) endEvent() {
+	v.events--
+	if v.events == 0 {
+		v.lock.Unlock()
+	}
+}
+
+// handle returns fn as an event handler that holds the viewer lock.
+func (v *viewer) handle(fn func()) func() {
+	return func() {
+		v.startEvent()
+		defer v.endEvent()
+		fn()
+	}
+}
+
+// handleCheck returns fn as a check box handler that holds the viewer lock.
+func (v *viewer) handleCheck(fn func(bool)) func(bool) {
+	return func(on bool) {
+		v.startEvent()
+		defer v.endEvent()
+		fn(on)
+	}
+}
+
 func (v *viewer) loadDir(dir fyne.ListableURI) {
-	var (
-		data   dicom.Dataset
-		frames []*frame.Frame
-	)
+	v.active.loadDir(dir)
+	v.refreshActive()
+}
+
+func (v *viewer) loadFile(r io.ReadCloser, length int64) {
+	v.active.loadFile(r, length)
+	v.refreshActive()
+}
+
+// loadDir lists the series in a folder in the browser and shows the first of them,
+// applying any presentation states that the folder also contains.
+func (vp *viewport) loadDir(dir fyne.ListableURI) {
+	var images []*dicom.Dataset
+	var states []*dicomgraphics.PresentationState
 
 	files, _ := dir.List()
 	for i, file := range files {
 		r, _ := storage.Reader(file)
 		d, err := dicom.Parse(r, fileLength(file.Path()), nil)
-		if i == 0 {
-			if err != nil {
-				fyne.LogError("First file in dir was not DICOM", err)
-				return
-			}
-			data = d
+		if i == 0 && err != nil {
+			fyne.LogError("First file in dir was not DICOM", err)
+			return
 		}
 		if err != nil {
 			fyne.LogError("Could not open dicom file "+file.Name()+" in folder", err)
 			continue
 		}
+		_ = r.Close()
 
-		t, err := d.FindElementByTag(tag.PixelData)
-		if err == nil {
-			frames = append(frames, t.Value.GetValue().(dicom.PixelDataInfo).Frames...)
+		if state, err := dicomgraphics.ParsePresentationState(&d); err == nil {
+			states = append(states, state)
+			continue
+		} else if err != dicomgraphics.ErrNotPresentationState {
+			fyne.LogError("Could not apply presentation state "+file.Name()+" in folder", err)
+			continue
 		}
-		_ = r.Close()
+		images = append(images, &d)
 	}
 
-	t, err := data.FindElementByTag(tag.PixelData)
-	if err == nil {
-		info := t.Value.GetValue().(dicom.PixelDataInfo)
-		info.Frames = frames
-		v, _ := dicom.NewValue(info)
-		t.Value = v
+	studies := dicomgraphics.GroupStudies(images)
+	var first *dicomgraphics.StudySeries
+	for _, study := range studies {
+		for _, series := range study.Series {
+			if first == nil && series.Len() > 0 {
+				first = series
+			}
+		}
+	}
+	if first == nil {
+		fyne.LogError("No images found in folder "+dir.Name(), nil)
+		return
 	}
 
-	v.loadImage(data)
+	vp.parent.setStudies(studies)
+	vp.loadSeries(first.Series, first.Slices[0].Data)
+	for _, state := range states {
+		vp.applyPresentationState(state)
+	}
 }
 
-func (v *viewer) loadFile(r io.ReadCloser, length int64) {
+// loadFile shows the images in a file, or restores the view it describes if it is a presentation state.
+func (vp *viewport) loadFile(r io.ReadCloser, length int64) {
 	data, err := dicom.Parse(r, length, nil)
 	if err != nil {
-		dialog.ShowError(err, v.win)
+		dialog.ShowError(err, vp.parent.win)
 		return
 	}
 
 	err = r.Close()
 
-	v.loadImage(data)
-}
-
-func (v *viewer) loadImage(data dicom.Dataset) {
-	for _, elem := range data.Elements {
-		if elem.Tag == tag.PixelData {
-			v.frames = elem.Value.GetValue().(dicom.PixelDataInfo).Frames
-
-			if len(v.frames) == 0 {
-				panic("No images found")
-			}
-
-			v.setFrame(0)
-		} else if elem.Tag == tag.PatientName {
-			v.name.SetText(fmt.Sprintf("%v", elem.Value))
-		} else if elem.Tag == tag.PatientID {
-			v.id.SetText(fmt.Sprintf("%v", elem.Value))
-		} else if elem.Tag == tag.StudyDescription {
-			v.study.SetText(fmt.Sprintf("%v", elem.Value))
-		} else if elem.Tag == tag.WindowCenter {
-			str := fmt.Sprintf("%v", elem.Value.GetValue().([]string)[0])
-			l, _ := strconv.Atoi(str)
-			v.dicom.SetWindowLevel(int16(l))
-			v.level.SetText(str)
-		} else if elem.Tag == tag.WindowWidth {
-			str := fmt.Sprintf("%v", elem.Value.GetValue().([]string)[0])
-			l, _ := strconv.Atoi(str)
-			v.dicom.SetWindowWidth(int16(l))
-			v.width.SetText(str)
-		}
+	if state, err := dicomgraphics.ParsePresentationState(&data); err == nil {
+		vp.parent.applyPresentationState(state)
+		return
+	} else if err != dicomgraphics.ErrNotPresentationState {
+		dialog.ShowError(err, vp.parent.win)
+		return
 	}
+	vp.loadImage(&data)
 }
 
 func (v *viewer) loadKeys() {
 	v.win.Canvas().SetOnTypedKey(func(key *fyne.KeyEvent) {
+		v.startEvent()
+		defer v.endEvent()
+
 		switch key.Name {
 		case fyne.KeyUp:
 			v.nextFrame()
@@ -124,30 +181,120 @@ func (v *viewer) loadKeys() {
 			v.previousFrame()
 		case fyne.KeyF:
 			v.fullScreen()
+		case fyne.KeySpace:
+			v.player.toggle()
+		case fyne.KeyDelete, fyne.KeyBackspace:
+			v.active.deleteSelected()
+		case fyne.KeyO:
+			v.overlays.SetChecked(!v.showOverlays)
+		case fyne.KeyC:
+			v.cornerText.SetChecked(!v.showCorners)
+		case fyne.KeyB:
+			v.scaleBar.SetChecked(!v.showScaleBar)
+		case fyne.KeyR:
+			v.rotate(90)
+		case fyne.KeyL:
+			v.rotate(-90)
+		case fyne.KeyH:
+			v.flipHorizontal()
+		case fyne.KeyV:
+			v.flipVertical()
+		case fyne.KeyI:
+			v.active.toggleInverse()
+		case fyne.KeyT:
+			v.showTags()
+		case fyne.KeyP:
+			v.printToPDF()
 		}
 	})
+
+	if c, ok := v.win.Canvas().(desktop.Canvas); ok {
+		c.SetOnKeyDown(func(key *fyne.KeyEvent) {
+			if key.Name == desktop.KeyControlLeft || key.Name == desktop.KeyControlRight {
+				v.ctrlDown = true
+			}
+		})
+		c.SetOnKeyUp(func(key *fyne.KeyEvent) {
+			if key.Name == desktop.KeyControlLeft || key.Name == desktop.KeyControlRight {
+				v.ctrlDown = false
+			}
+		})
+	}
 }
 
 func (v *viewer) nextFrame() {
-	v.setFrame(v.currentFrame + 1)
+	v.setFrame(v.active.currentFrame + 1)
 }
 
 func (v *viewer) previousFrame() {
-	v.setFrame(v.currentFrame - 1)
+	v.setFrame(v.active.currentFrame - 1)
 }
 
 func (v *viewer) setFrame(id int) {
-	count := len(v.frames)
-	if id > count-1 {
-		id = 0
-	} else if id < 0 {
-		id = count - 1
+	v.active.setFrame(id)
+	if v.syncScroll {
+		plane := v.active.plane()
+		for _, vp := range v.visibleViewports() {
+			if vp == v.active {
+				continue
+			}
+
+			if i := vp.series.Nearest(plane); i >= 0 {
+				vp.setFrame(i)
+			}
+		}
 	}
-	v.currentFrame = id
 
-	v.dicom.SetFrame(&v.frames[id].NativeData)
-	canvas.Refresh(v.image)
-	v.frame.SetText(fmt.Sprintf("%d/%d", id+1, count))
+	v.frame.SetText(fmt.Sprintf("%d/%d", v.active.currentFrame+1, v.active.series.Len()))
+	// rebuilding the tags for every frame would slow playback, so they follow once it is paused
+	if !v.player.playing() {
+		v.refreshTags()
+	}
+}
+
+// rotate turns the image in the active viewport clockwise by a multiple of 90 degrees.
+func (v *viewer) rotate(degrees int) {
+	v.active.setTransform(v.active.transform.Rotate(degrees))
+}
+
+func (v *viewer) flipHorizontal() {
+	v.active.setTransform(v.active.transform.FlipHorizontal())
+}
+
+func (v *viewer) flipVertical() {
+	v.active.setTransform(v.active.transform.FlipVertical())
+}
+
+func (v *viewer) setShowOverlays(show bool) {
+	v.showOverlays = show
+	for _, vp := range v.viewports {
+		vp.refreshOverlays()
+	}
+}
+
+func (v *viewer) setShowCorners(show bool) {
+	v.showCorners = show
+	for _, vp := range v.viewports {
+		vp.refreshOverlay()
+	}
+}
+
+func (v *viewer) setShowScaleBar(show bool) {
+	v.showScaleBar = show
+	for _, vp := range v.viewports {
+		vp.refreshOverlay()
+	}
+}
+
+func (v *viewer) setWindow(level, width int16) {
+	if !v.syncWindow {
+		v.active.setWindow(level, width)
+		return
+	}
+
+	for _, vp := range v.visibleViewports() {
+		vp.setWindow(level, width)
+	}
 }
 
 func fileLength(path string) int64 {
@@ -166,12 +313,22 @@ func fileLength(path string) int64 {
 }
 
 func main() {
-	a := app.New()
+	a := app.NewWithID("com.fynelabs.dicomviewer")
 	a.SetIcon(resourceIconPng)
 
 	ui := makeUI(a)
-	if len(os.Args) > 1 {
-		path := os.Args[1]
+	paths := os.Args[1:]
+	if len(paths) > 1 {
+		ui.layout.SetSelected(layoutFor(len(paths)))
+	}
+	var states []*dicomgraphics.PresentationState
+	loaded := 0
+	for _, path := range paths {
+		if loaded >= len(ui.viewports) {
+			log.Println("Too many paths for the largest layout, ignoring:", path)
+			break
+		}
+		vp := ui.viewports[loaded]
 
 		info, err := os.Stat(path)
 		if err == nil && info.IsDir() {
@@ -180,16 +337,31 @@ func main() {
 				log.Println("Failed to open folder at path:", path)
 				return
 			}
-			ui.loadDir(dir)
+			vp.loadDir(dir)
 		} else {
-			r, err := os.Open(path)
+			data, err := dicom.ParseFile(path, nil)
 			if err != nil {
 				log.Println("Failed to load file at path:", path)
 				return
 			}
-			ui.loadFile(r, fileLength(path))
+			if state, err := dicomgraphics.ParsePresentationState(&data); err == nil {
+				states = append(states, state)
+				continue
+			} else if err != dicomgraphics.ErrNotPresentationState {
+				log.Println("Failed to apply presentation state at path:", path, err)
+				continue
+			}
+			vp.loadImage(&data)
 		}
+		loaded++
+	}
+	if len(states) > 0 && loaded > 0 {
+		ui.layout.SetSelected(layoutFor(loaded))
+	}
+	for _, state := range states {
+		ui.applyPresentationState(state)
 	}
+	ui.refreshActive()
 
 	ui.loadKeys()
 	ui.win.ShowAndRun()
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/fynelabs/dicomgraphics"
+)
+
+const (
+	handleRadius     = 4
+	minRulerWidth    = 3
+	freehandStepSize = 3
+	// editWhole is the edit point of a freehand region that is dragged as a whole, rather than one of its points.
+	editWhole = -1
+)
+
+var (
+	measureColor  = color.NRGBA{R: 0xff, G: 0xd7, A: 0xff}
+	selectedColor = color.NRGBA{G: 0xd7, B: 0xff, A: 0xff}
+
+	measureKinds = map[mouseTool]dicomgraphics.MeasurementKind{
+		toolRuler:     dicomgraphics.MeasureRuler,
+		toolAngle:     dicomgraphics.MeasureAngle,
+		toolCobb:      dicomgraphics.MeasureCobb,
+		toolRectangle: dicomgraphics.MeasureRectangle,
+		toolEllipse:   dicomgraphics.MeasureEllipse,
+		toolFreehand:  dicomgraphics.MeasureFreehand,
+	}
+)
+
+func (vp *viewport) currentSlice() *dicomgraphics.Slice {
+	if vp.series.Len() == 0 {
+		return nil
+	}
+
+	return vp.series.Slices[vp.currentFrame]
+}
+
+func (vp *viewport) calibration() dicomgraphics.Calibration {
+	slice := vp.currentSlice()
+	if slice == nil {
+		return dicomgraphics.Calibration{}
+	}
+
+	return dicomgraphics.PixelCalibration(slice.Data)
+}
+
+func (vp *viewport) imagePoint(pos fyne.Position) dicomgraphics.Point {
+	x, y := vp.imagePosition(pos)
+	return dicomgraphics.Point{X: x, Y: y}
+}
+
+// startEdit selects the measurement with a point under pos, ready to drag it.
+// A freehand region has too many points to move each, so it is dragged as a whole from any point of its outline.
+func (vp *viewport) startEdit(pos fyne.Position) bool {
+	slice := vp.currentSlice()
+	if slice == nil {
+		return false
+	}
+
+	for _, m := range vp.measurements[slice] {
+		for i, p := range m.Points {
+			handle := vp.widgetPosition(p.X, p.Y)
+			if dx, dy := handle.X-pos.X, handle.Y-pos.Y; dx*dx+dy*dy <= handleRadius*handleRadius*4 {
+				vp.selected = m
+				vp.pending = m
+				vp.editPoint = i
+				if m.Kind == dicomgraphics.MeasureFreehand {
+					vp.editPoint = editWhole
+				}
+				vp.refreshOverlay()
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// startMeasurement begins a new measurement or adds the next stroke to one in progress.
+func (vp *viewport) startMeasurement(kind dicomgraphics.MeasurementKind, pos fyne.Position) {
+	slice := vp.currentSlice()
+	if slice == nil {
+		return
+	}
+
+	p := vp.imagePoint(pos)
+	if vp.pending != nil && vp.pending.Kind != kind {
+		vp.cancelMeasurement()
+	}
+	if kind == dicomgraphics.MeasureFreehand {
+		vp.pending = &dicomgraphics.Measurement{Kind: kind, Points: []dicomgraphics.Point{p}}
+		vp.measurements[slice] = append(vp.measurements[slice], vp.pending)
+	} else if vp.pending == nil {
+		vp.pending = &dicomgraphics.Measurement{Kind: kind, Points: []dicomgraphics.Point{p, p}}
+		vp.measurements[slice] = append(vp.measurements[slice], vp.pending)
+	} else if kind == dicomgraphics.MeasureCobb {
+		vp.pending.Points = append(vp.pending.Points, p, p)
+	} else {
+		vp.pending.Points = append(vp.pending.Points, p)
+	}
+
+	vp.selected = vp.pending
+	vp.editPoint = len(vp.pending.Points) - 1
+	delete(vp.stats, vp.pending)
+	vp.refreshOverlay()
+}
+
+func (vp *viewport) moveMeasurement(pos fyne.Position) {
+	if vp.pending == nil {
+		return
+	}
+
+	p := vp.imagePoint(pos)
+	if vp.editPoint == editWhole {
+		last := vp.imagePoint(vp.dragLast)
+		for i := range vp.pending.Points {
+			vp.pending.Points[i].X += p.X - last.X
+			vp.pending.Points[i].Y += p.Y - last.Y
+		}
+	} else if vp.pending.Kind == dicomgraphics.MeasureFreehand {
+		last := vp.pending.Points[len(vp.pending.Points)-1]
+		lastPos := vp.widgetPosition(last.X, last.Y)
+		if dx, dy := pos.X-lastPos.X, pos.Y-lastPos.Y; dx*dx+dy*dy < freehandStepSize*freehandStepSize {
+			return
+		}
+		vp.pending.Points = append(vp.pending.Points, p)
+	} else {
+		vp.pending.Points[vp.editPoint] = p
+	}
+	delete(vp.stats, vp.pending)
+	vp.refreshOverlay()
+}
+
+// endMeasurement finishes a stroke, discarding a new measurement if it was just a click.
+func (vp *viewport) endMeasurement(editing bool) {
+	m := vp.pending
+	if m == nil {
+		return
+	}
+
+	if !editing && len(m.Points) == 2 {
+		start, end := vp.widgetPosition(m.Points[0].X, m.Points[0].Y), vp.widgetPosition(m.Points[1].X, m.Points[1].Y)
+		if dx, dy := end.X-start.X, end.Y-start.Y; dx*dx+dy*dy < minRulerWidth*minRulerWidth {
+			vp.removeMeasurement(m)
+			vp.pending = nil
+			vp.selected = nil
+			vp.refreshOverlay()
+			return
+		}
+	}
+
+	if m.Kind == dicomgraphics.MeasureFreehand && !m.Complete() {
+		vp.removeMeasurement(m)
+		vp.selected = nil
+	}
+	if m.Complete() || m.Kind == dicomgraphics.MeasureFreehand {
+		vp.pending = nil
+	}
+	vp.refreshOverlay()
+}
+
+// addText asks for a note to place at pos on the current slice.
+func (vp *viewport) addText(pos fyne.Position) {
+	slice := vp.currentSlice()
+	if slice == nil {
+		return
+	}
+
+	anchor := vp.imagePoint(pos)
+	entry := widget.NewEntry()
+	dialog.ShowForm("Add Text", "Add", "Cancel", []*widget.FormItem{widget.NewFormItem("Text", entry)},
+		func(ok bool) {
+			vp.parent.startEvent()
+			defer vp.parent.endEvent()
+
+			if !ok || entry.Text == "" {
+				return
+			}
+
+			vp.texts[slice] = append(vp.texts[slice], dicomgraphics.TextAnnotation{Text: entry.Text, Anchor: anchor})
+			vp.refreshOverlay()
+		}, vp.parent.win)
+}
+
+// cancelMeasurement removes any measurement that has not been completed.
+func (vp *viewport) cancelMeasurement() {
+	if vp.pending == nil {
+		return
+	}
+
+	if !vp.pending.Complete() {
+		vp.removeMeasurement(vp.pending)
+	}
+	vp.pending = nil
+	vp.refreshOverlay()
+}
+
+func (vp *viewport) clearMeasurements() {
+	slice := vp.currentSlice()
+	if slice == nil {
+		return
+	}
+
+	delete(vp.measurements, slice)
+	delete(vp.graphics, slice)
+	delete(vp.texts, slice)
+	vp.pending = nil
+	vp.selected = nil
+	vp.refreshOverlay()
+}
+
+func (vp *viewport) deleteSelected() {
+	if vp.selected == nil {
+		return
+	}
+
+	vp.removeMeasurement(vp.selected)
+	if vp.pending == vp.selected {
+		vp.pending = nil
+	}
+	vp.selected = nil
+	vp.refreshOverlay()
+}
+
+func (vp *viewport) removeMeasurement(m *dicomgraphics.Measurement) {
+	for slice, list := range vp.measurements {
+		for i, item := range list {
+			if item == m {
+				vp.measurements[slice] = append(list[:i], list[i+1:]...)
+				delete(vp.stats, m)
+				return
+			}
+		}
+	}
+}
+
+// refreshOverlay redraws the annotations of the current slice at the current zoom and pan.
+func (vp *viewport) refreshOverlay() {
+	if vp.overlay == nil {
+		return
+	}
+
+	var objs []fyne.CanvasObject
+	if slice := vp.currentSlice(); slice != nil {
+		cal := vp.calibration()
+		for _, m := range vp.measurements[slice] {
+			objs = append(objs, vp.measurementObjects(m, cal)...)
+		}
+		for _, g := range vp.graphics[slice] {
+			objs = append(objs, vp.graphicObjects(g)...)
+		}
+		for _, t := range vp.texts[slice] {
+			pos := vp.annotationPosition(t.Anchor, t.Display).Subtract(fyne.NewPos(handleRadius*2, handleRadius*2))
+			objs = append(objs, vp.labelObjects([]string{t.Text}, pos, measureColor)...)
+		}
+		if vp.parent.showCorners {
+			objs = append(objs, vp.cornerObjects()...)
+		}
+		if vp.parent.showScaleBar {
+			objs = append(objs, vp.scaleBarObjects()...)
+		}
+	}
+
+	vp.overlay.Objects = objs
+	vp.overlay.Refresh()
+}
+
+func (vp *viewport) measurementObjects(m *dicomgraphics.Measurement, cal dicomgraphics.Calibration) []fyne.CanvasObject {
+	c := measureColor
+	if m == vp.selected {
+		c = selectedColor
+	}
+
+	var objs []fyne.CanvasObject
+	positions := make([]fyne.Position, len(m.Points))
+	for i, p := range m.Points {
+		positions[i] = vp.widgetPosition(p.X, p.Y)
+	}
+
+	line := func(from, to fyne.Position) {
+		l := canvas.NewLine(c)
+		l.StrokeWidth = 1.5
+		l.Position1, l.Position2 = from, to
+		objs = append(objs, l)
+	}
+	switch m.Kind {
+	case dicomgraphics.MeasureCobb:
+		line(positions[0], positions[1])
+		if len(positions) >= 4 {
+			line(positions[2], positions[3])
+		}
+	case dicomgraphics.MeasureRectangle:
+		corner1, corner2 := fyne.NewPos(positions[0].X, positions[1].Y), fyne.NewPos(positions[1].X, positions[0].Y)
+		line(positions[0], corner1)
+		line(corner1, positions[1])
+		line(positions[1], corner2)
+		line(corner2, positions[0])
+	case dicomgraphics.MeasureEllipse:
+		e := canvas.NewCircle(color.Transparent)
+		e.StrokeColor = c
+		e.StrokeWidth = 1.5
+		e.Position1, e.Position2 = positions[0], positions[1]
+		if e.Position1.X > e.Position2.X {
+			e.Position1.X, e.Position2.X = e.Position2.X, e.Position1.X
+		}
+		if e.Position1.Y > e.Position2.Y {
+			e.Position1.Y, e.Position2.Y = e.Position2.Y, e.Position1.Y
+		}
+		objs = append(objs, e)
+	case dicomgraphics.MeasureFreehand:
+		for i := 1; i < len(positions); i++ {
+			line(positions[i-1], positions[i])
+		}
+		if m != vp.pending {
+			line(positions[len(positions)-1], positions[0])
+		}
+		return append(objs, vp.labelObjects(vp.regionLabel(m, cal), positions[0], c)...)
+	default:
+		for i := 1; i < len(positions); i++ {
+			line(positions[i-1], positions[i])
+		}
+	}
+
+	for _, pos := range positions {
+		h := canvas.NewCircle(color.Transparent)
+		h.StrokeColor = c
+		h.StrokeWidth = 1
+		h.Move(pos.Subtract(fyne.NewPos(handleRadius, handleRadius)))
+		h.Resize(fyne.NewSize(handleRadius*2, handleRadius*2))
+		objs = append(objs, h)
+	}
+
+	label := []string{m.Label(cal)}
+	if m.IsRegion() {
+		label = vp.regionLabel(m, cal)
+	}
+	return append(objs, vp.labelObjects(label, positions[len(positions)-1], c)...)
+}
+
+// annotationPosition returns where a point of a saved annotation is shown,
+// display points are fractions of the viewport rather than image coordinates.
+func (vp *viewport) annotationPosition(p dicomgraphics.Point, display bool) fyne.Position {
+	if display {
+		return fyne.NewPos(float32(p.X)*vp.Size().Width, float32(p.Y)*vp.Size().Height)
+	}
+
+	return vp.widgetPosition(p.X, p.Y)
+}
+
+// graphicObjects draws a graphic loaded from a presentation state, which cannot be edited.
+func (vp *viewport) graphicObjects(g dicomgraphics.Graphic) []fyne.CanvasObject {
+	outline := g.Outline()
+	positions := make([]fyne.Position, len(outline))
+	for i, p := range outline {
+		positions[i] = vp.annotationPosition(p, g.Display)
+	}
+
+	var objs []fyne.CanvasObject
+	line := func(from, to fyne.Position) {
+		l := canvas.NewLine(measureColor)
+		l.StrokeWidth = 1.5
+		l.Position1, l.Position2 = from, to
+		objs = append(objs, l)
+	}
+	if g.Type == "POINT" || len(positions) == 1 {
+		for _, pos := range positions {
+			line(pos.Subtract(fyne.NewPos(1, 0)), pos.Add(fyne.NewPos(1, 0)))
+		}
+		return objs
+	}
+	for i := 1; i < len(positions); i++ {
+		line(positions[i-1], positions[i])
+	}
+	return objs
+}
+
+func (vp *viewport) labelObjects(lines []string, pos fyne.Position, c color.Color) []fyne.CanvasObject {
+	var objs []fyne.CanvasObject
+	pos = pos.Add(fyne.NewPos(handleRadius*2, handleRadius*2))
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		text := canvas.NewText(line, c)
+		text.TextSize = theme.CaptionTextSize()
+		text.Move(pos)
+		text.Resize(text.MinSize())
+		objs = append(objs, text)
+		pos.Y += text.MinSize().Height
+	}
+	return objs
+}
+
+// regionLabel returns the statistics of a region, in the units of the current slice.
+// They are kept until the region is edited, rather than found again each time the overlay is drawn.
+func (vp *viewport) regionLabel(m *dicomgraphics.Measurement, cal dicomgraphics.Calibration) []string {
+	stats, ok := vp.stats[m]
+	if !ok {
+		if stats, ok = vp.dicom.RegionStatistics(m, cal); !ok {
+			return nil
+		}
+		vp.stats[m] = stats
+	}
+
+	units := dicomgraphics.ModalityUnits(vp.currentSlice().Data)
+	return []string{
+		m.Label(cal),
+		fmt.Sprintf("Mean %.1f SD %.1f %s", stats.Mean, stats.StdDev, units),
+		fmt.Sprintf("Min %.1f Max %.1f %s", stats.Min, stats.Max, units),
+		fmt.Sprintf("%d px", stats.Count),
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"image"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/fynelabs/dicomgraphics"
+)
+
+// The images of the active viewport that can be printed.
+const (
+	printCurrent   = "Current image"
+	printAnnotated = "Annotated images"
+	printSeries    = "Whole series"
+)
+
+// printLayouts are the grids of columns and rows offered for each page.
+var printLayouts = []string{"1x1", "1x2", "2x2", "2x3", "3x4", "4x5"}
+
+var errNothingToPrint = errors.New("there are no annotated images to print")
+
+// printToPDF asks how to lay out the images of the active viewport, then saves them as a PDF film sheet
+// drawn as they are displayed, with measurements and the visible corner text burned in.
+func (v *viewer) printToPDF() {
+	vp := v.active
+	if vp.currentSlice() == nil {
+		return
+	}
+
+	layout := widget.NewSelect(printLayouts, nil)
+	layout.SetSelected("3x4")
+	var paperNames []string
+	for _, p := range dicomgraphics.PaperSizes {
+		paperNames = append(paperNames, p.Name)
+	}
+	paper := widget.NewSelect(paperNames, nil)
+	paper.SetSelected(paperNames[0])
+	landscape := widget.NewCheck("Landscape", nil)
+	images := widget.NewRadioGroup([]string{printCurrent, printAnnotated, printSeries}, nil)
+	images.SetSelected(printSeries)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Layout", layout),
+		widget.NewFormItem("Paper", paper),
+		widget.NewFormItem("", landscape),
+		widget.NewFormItem("Images", images),
+	}
+	dialog.ShowForm("Print to PDF", "Save", "Cancel", items, func(ok bool) {
+		v.startEvent()
+		defer v.endEvent()
+
+		if !ok {
+			return
+		}
+
+		slices := vp.printSlices(images.Selected)
+		if len(slices) == 0 {
+			dialog.ShowError(errNothingToPrint, v.win)
+			return
+		}
+		cols, rows, err := dicomgraphics.ParseGrid(layout.Selected)
+		if err != nil {
+			dialog.ShowError(err, v.win)
+			return
+		}
+		sheet := dicomgraphics.NewFilmSheet(vp.series.Slices[slices[0]].Data, cols, rows)
+		if size, ok := dicomgraphics.FindPaperSize(paper.Selected); ok {
+			sheet.Paper = size
+		}
+		if landscape.Checked {
+			sheet.Paper = sheet.Paper.Landscape()
+		}
+		v.savePDF(vp, slices, sheet)
+	}, v.win)
+}
+
+// printSlices returns the indexes of the slices to print from this viewport.
+func (vp *viewport) printSlices(choice string) []int {
+	switch choice {
+	case printCurrent:
+		return []int{vp.currentFrame}
+	case printAnnotated:
+		var annotated []int
+		for i, slice := range vp.series.Slices {
+			if len(vp.measurements[slice]) > 0 || len(vp.graphics[slice]) > 0 || len(vp.texts[slice]) > 0 {
+				annotated = append(annotated, i)
+			}
+		}
+		return annotated
+	}
+
+	all := make([]int, vp.series.Len())
+	for i := range all {
+		all[i] = i
+	}
+	return all
+}
+
+func (v *viewer) savePDF(vp *viewport, slices []int, sheet dicomgraphics.FilmSheet) {
+	d := dialog.NewFileSave(func(w fyne.URIWriteCloser, err error) {
+		v.startEvent()
+		defer v.endEvent()
+
+		if w == nil || err != nil {
+			return
+		}
+		opts := dicomgraphics.FilmImageOptions{State: vp.presentationState(), Annotate: v.showCorners,
+			Corners: v.corners, Overlays: v.showOverlays}
+		images := make([]image.Image, len(slices))
+		for i, index := range slices {
+			images[i] = dicomgraphics.RenderFilmImage(vp.series.Slices[index], index, vp.series.Len(), opts)
+		}
+		err = dicomgraphics.EncodeFilmSheet(w, images, sheet)
+		// the file is only complete once it is closed, so a failure to close is a failure to save
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			dialog.ShowError(err, v.win)
+		}
+	}, v.win)
+	d.SetFileName("film.pdf")
+	d.SetFilter(storage.NewExtensionFileFilter([]string{".pdf"}))
+	d.Show()
+}
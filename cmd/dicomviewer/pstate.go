@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+
+	"github.com/fynelabs/dicomgraphics"
+)
+
+var errNotReferenced = errors.New("the presentation state does not reference any of the loaded images")
+
+func (v *viewer) savePresentationState() {
+	vp := v.active
+	slice := vp.currentSlice()
+	if slice == nil {
+		return
+	}
+
+	d := dialog.NewFileSave(func(w fyne.URIWriteCloser, err error) {
+		v.startEvent()
+		defer v.endEvent()
+
+		if w == nil || err != nil {
+			return
+		}
+		err = vp.presentationState().Write(w, slice.Data)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			dialog.ShowError(err, v.win)
+		}
+	}, v.win)
+	d.SetFileName("presentation.dcm")
+	d.SetFilter(storage.NewExtensionFileFilter([]string{".dcm"}))
+	d.Show()
+}
+
+// applyPresentationState restores a saved view in every visible viewport showing the images it references.
+func (v *viewer) applyPresentationState(state *dicomgraphics.PresentationState) {
+	applied := false
+	for _, vp := range v.visibleViewports() {
+		if vp.applyPresentationState(state) {
+			applied = true
+		}
+	}
+
+	if !applied {
+		dialog.ShowError(errNotReferenced, v.win)
+		return
+	}
+	v.refreshActive()
+}
+
+// presentationState describes the window, displayed area and annotations of the series in this viewport.
+func (vp *viewport) presentationState() *dicomgraphics.PresentationState {
+	state := &dicomgraphics.PresentationState{Label: "VIEWER", Description: vp.study,
+		Inverse: vp.dicom.Inverse(), Rotation: vp.transform.Rotation, Flip: vp.transform.Flip}
+	// the shutter of the images stays with them, only one from a presentation state that was applied is saved
+	if vp.state != nil {
+		state.Shutter = vp.state.Shutter
+	}
+	state.Windows = []dicomgraphics.Window{{
+		Level: float64(vp.dicom.WindowLevel()), Width: float64(vp.dicom.WindowWidth())}}
+
+	for _, slice := range vp.series.Slices {
+		ref := dicomgraphics.NewImageReference(slice)
+		state.Images = append(state.Images, ref)
+		if len(vp.measurements[slice]) == 0 && len(vp.graphics[slice]) == 0 && len(vp.texts[slice]) == 0 {
+			continue
+		}
+
+		state.Annotations = append(state.Annotations, &dicomgraphics.Annotation{Image: ref,
+			Measurements: vp.measurements[slice], Graphics: vp.graphics[slice], Texts: vp.texts[slice],
+			Calibration: dicomgraphics.PixelCalibration(slice.Data)})
+	}
+
+	area := dicomgraphics.DisplayedArea{SizeMode: dicomgraphics.SizeScaleToFit}
+	if vp.area != nil {
+		area.TopLeft, area.BottomRight = vp.area[0], vp.area[1]
+		state.DisplayedAreas = append(state.DisplayedAreas, area)
+	} else if vp.zoom != 1 || vp.pan != fyne.NewPos(0, 0) {
+		// the corners of the viewport swap over when the image is rotated or flipped
+		a, b := vp.imagePoint(fyne.NewPos(0, 0)), vp.imagePoint(fyne.NewPos(vp.Size().Width, vp.Size().Height))
+		area.TopLeft = dicomgraphics.Point{X: math.Min(a.X, b.X), Y: math.Min(a.Y, b.Y)}
+		area.BottomRight = dicomgraphics.Point{X: math.Max(a.X, b.X), Y: math.Max(a.Y, b.Y)}
+		state.DisplayedAreas = append(state.DisplayedAreas, area)
+	}
+	return state
+}
+
+// applyPresentationState restores a saved view if any of the slices in this viewport are referenced.
+// The annotations of the referenced slices are replaced by those of the state, so applying it again adds nothing.
+// The first annotated slice is shown, and false is returned if the state does not apply to this series.
+func (vp *viewport) applyPresentationState(state *dicomgraphics.PresentationState) bool {
+	first, annotated := -1, -1
+	for i, slice := range vp.series.Slices {
+		if !state.References(dicomgraphics.NewImageReference(slice)) {
+			continue
+		}
+		if first < 0 {
+			vp.cancelMeasurement()
+			vp.selected = nil
+			first = i
+		}
+
+		if vp.replaceAnnotations(slice, state) && annotated < 0 {
+			annotated = i
+		}
+	}
+	if first < 0 {
+		return false
+	}
+
+	// the rescale of the state may change the region statistics
+	vp.state = state
+	vp.stats = make(map[*dicomgraphics.Measurement]dicomgraphics.Statistics)
+	if annotated >= 0 {
+		vp.setFrame(annotated)
+	} else if !state.References(dicomgraphics.NewImageReference(vp.currentSlice())) {
+		vp.setFrame(first)
+	}
+
+	ref := dicomgraphics.NewImageReference(vp.currentSlice())
+	state.Apply(vp.dicom, ref)
+	vp.transform = dicomgraphics.Transform{Rotation: state.Rotation, Flip: state.Flip}
+	if area, ok := state.DisplayedAreaFor(ref); ok {
+		vp.area = &[2]dicomgraphics.Point{area.TopLeft, area.BottomRight}
+	} else {
+		vp.zoom, vp.pan, vp.area = 1, fyne.NewPos(0, 0), nil
+	}
+	vp.fitArea()
+	vp.refreshImage()
+	return true
+}
+
+// replaceAnnotations sets the measurements, graphics and text of a slice to those a presentation state has for it,
+// returning true if it has any.
+func (vp *viewport) replaceAnnotations(slice *dicomgraphics.Slice, state *dicomgraphics.PresentationState) bool {
+	delete(vp.measurements, slice)
+	delete(vp.graphics, slice)
+	delete(vp.texts, slice)
+
+	annotations := state.AnnotationsFor(dicomgraphics.NewImageReference(slice))
+	for _, a := range annotations {
+		for _, m := range a.Measurements {
+			points := append([]dicomgraphics.Point{}, m.Points...)
+			vp.measurements[slice] = append(vp.measurements[slice], &dicomgraphics.Measurement{Kind: m.Kind, Points: points})
+		}
+		vp.graphics[slice] = append(vp.graphics[slice], a.Graphics...)
+		vp.texts[slice] = append(vp.texts[slice], a.Texts...)
+	}
+	return len(annotations) > 0
+}
+
+// fitArea zooms and pans so that the saved image area fills the viewport.
+func (vp *viewport) fitArea() {
+	size := vp.Size()
+	if vp.area == nil || size.Width <= 0 || size.Height <= 0 || vp.dicom.Bounds().Empty() {
+		return
+	}
+
+	topLeft, bottomRight := vp.area[0], vp.area[1]
+	w, h := float32(bottomRight.X-topLeft.X), float32(bottomRight.Y-topLeft.Y)
+	if w <= 0 || h <= 0 {
+		return
+	}
+	if vp.transform.Swapped() {
+		w, h = h, w
+	}
+
+	vp.zoom, vp.pan = 1, fyne.NewPos(0, 0)
+	_, fit := vp.imageTransform(size)
+	scale := size.Width / w
+	if s := size.Height / h; s < scale {
+		scale = s
+	}
+	vp.zoom = scale / fit
+
+	centre := vp.widgetPosition((topLeft.X+bottomRight.X)/2, (topLeft.Y+bottomRight.Y)/2)
+	vp.pan = fyne.NewPos(size.Width/2, size.Height/2).Subtract(centre)
+	vp.redraw()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/fynelabs/dicomgraphics"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// tagBrowser is a window listing the elements of the instance shown in the active viewport.
+type tagBrowser struct {
+	win    fyne.Window
+	lock   *sync.Mutex
+	tree   *widget.Tree
+	filter *widget.Entry
+
+	data     *dicom.Dataset
+	all      []*dicomgraphics.TagNode
+	nodes    map[widget.TreeNodeID]*dicomgraphics.TagNode
+	children map[widget.TreeNodeID][]widget.TreeNodeID
+	selected *dicomgraphics.TagNode
+}
+
+// showTags opens the tag browser, or brings it to the front if it is already open.
+func (v *viewer) showTags() {
+	if v.tags != nil {
+		v.tags.win.RequestFocus()
+		return
+	}
+
+	b := &tagBrowser{win: fyne.CurrentApp().NewWindow("DICOM Tags"), lock: &v.lock}
+	b.tree = widget.NewTree(b.childIDs, b.isBranch, b.createItem, b.updateItem)
+	b.tree.OnSelected = func(id widget.TreeNodeID) {
+		b.handle(func() {
+			b.selected = b.nodes[id]
+		})()
+	}
+	b.filter = widget.NewEntry()
+	b.filter.SetPlaceHolder("Filter by group, keyword or value")
+	b.filter.OnChanged = func(string) {
+		b.handle(b.refresh)()
+	}
+
+	copyNode := widget.NewButtonWithIcon("Copy", theme.ContentCopyIcon(), b.handle(func() {
+		if b.selected != nil {
+			b.win.Clipboard().SetContent(dicomgraphics.DumpTags([]*dicomgraphics.TagNode{b.selected}))
+		}
+	}))
+	copyAll := widget.NewButtonWithIcon("Copy All", theme.ContentCopyIcon(), b.handle(func() {
+		b.win.Clipboard().SetContent(dicomgraphics.DumpTags(dicomgraphics.FilterTags(b.all, b.filter.Text)))
+	}))
+	expand := widget.NewButton("Expand", func() {
+		b.tree.OpenAllBranches()
+	})
+	top := container.NewBorder(nil, nil, nil, container.NewHBox(expand, copyNode, copyAll), b.filter)
+	b.win.SetContent(container.NewBorder(top, nil, nil, nil, b.tree))
+	b.win.Resize(fyne.NewSize(720, 560))
+	b.win.SetOnClosed(b.handle(func() {
+		v.tags = nil
+	}))
+
+	v.tags = b
+	v.refreshTags()
+	b.win.Show()
+}
+
+// handle returns fn as an event handler of the tag browser that holds the viewer lock.
+// The browser is a separate window, whose events are delivered on another goroutine to those of the viewer,
+// so the lock is always taken rather than shared with an event of the viewer.
+func (b *tagBrowser) handle(fn func()) func() {
+	return func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		fn()
+	}
+}
+
+// refreshTags shows the instance of the current slice in the tag browser, if it is open.
+func (v *viewer) refreshTags() {
+	slice := v.active.currentSlice()
+	if v.tags == nil || slice == nil || slice.Data == v.tags.data {
+		return
+	}
+
+	v.tags.data = slice.Data
+	v.tags.all = dicomgraphics.TagTree(slice.Data)
+	v.tags.win.SetTitle("DICOM Tags - " + dicomgraphics.TagString(slice.Data, tag.SOPInstanceUID))
+	v.tags.refresh()
+}
+
+// refresh rebuilds the tree from the elements that match the filter.
+func (b *tagBrowser) refresh() {
+	b.nodes = make(map[widget.TreeNodeID]*dicomgraphics.TagNode)
+	b.children = make(map[widget.TreeNodeID][]widget.TreeNodeID)
+	b.addNodes("", dicomgraphics.FilterTags(b.all, b.filter.Text))
+	b.selected = nil
+	b.tree.UnselectAll()
+	b.tree.Refresh()
+}
+
+func (b *tagBrowser) addNodes(parent widget.TreeNodeID, nodes []*dicomgraphics.TagNode) {
+	for i, n := range nodes {
+		id := parent + "/" + strconv.Itoa(i)
+		b.nodes[id] = n
+		b.children[parent] = append(b.children[parent], id)
+		b.addNodes(id, n.Children)
+	}
+}
+
+func (b *tagBrowser) childIDs(id widget.TreeNodeID) []widget.TreeNodeID {
+	return b.children[id]
+}
+
+func (b *tagBrowser) isBranch(id widget.TreeNodeID) bool {
+	return id == "" || len(b.children[id]) > 0
+}
+
+func (b *tagBrowser) createItem(_ bool) fyne.CanvasObject {
+	l := widget.NewLabel("")
+	l.TextStyle.Monospace = true
+	return l
+}
+
+func (b *tagBrowser) updateItem(id widget.TreeNodeID, _ bool, item fyne.CanvasObject) {
+	n := b.nodes[id]
+	if n == nil {
+		return
+	}
+
+	if n.Item {
+		item.(*widget.Label).SetText(n.String())
+		return
+	}
+	item.(*widget.Label).SetText(fmt.Sprintf("%s %-36s %-2s %8d  %s", n.Tag, n.Keyword, n.VR, n.Length, n.Value))
+}
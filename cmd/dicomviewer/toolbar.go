@@ -2,10 +2,77 @@ package main
 
 import (
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
+// mouseTool is an action performed by dragging the mouse over a viewport.
+type mouseTool int
+
+const (
+	toolNone mouseTool = iota
+	toolWindow
+	toolPan
+	toolZoom
+	toolScroll
+	toolRuler
+	toolAngle
+	toolCobb
+	toolRectangle
+	toolEllipse
+	toolFreehand
+	toolText
+	toolEdit
+)
+
+const (
+	windowSensitivity = 2
+	scrollDragStep    = 10
+	zoomStep          = 1.1
+	minZoom           = 0.25
+	maxZoom           = 20
+)
+
+var (
+	toolNames = []string{
+		"Window",
+		"Pan",
+		"Zoom",
+		"Scroll",
+		"Ruler",
+		"Angle",
+		"Cobb angle",
+		"Rectangle ROI",
+		"Ellipse ROI",
+		"Freehand ROI",
+		"Text",
+	}
+
+	toolValues = map[string]mouseTool{
+		"Window":        toolWindow,
+		"Pan":           toolPan,
+		"Zoom":          toolZoom,
+		"Scroll":        toolScroll,
+		"Ruler":         toolRuler,
+		"Angle":         toolAngle,
+		"Cobb angle":    toolCobb,
+		"Rectangle ROI": toolRectangle,
+		"Ellipse ROI":   toolEllipse,
+		"Freehand ROI":  toolFreehand,
+		"Text":          toolText,
+	}
+)
+
+func defaultTools() map[desktop.MouseButton]mouseTool {
+	return map[desktop.MouseButton]mouseTool{
+		desktop.MouseButtonPrimary:   toolWindow,
+		desktop.MouseButtonSecondary: toolWindow,
+		desktop.MouseButtonTertiary:  toolPan,
+	}
+}
+
 type labelledAction struct {
 	label  string
 	icon   fyne.Resource
@@ -22,9 +89,55 @@ func (l *labelledAction) ToolbarObject() fyne.CanvasObject {
 	return b
 }
 
+type toolSelect struct {
+	label  string
+	button desktop.MouseButton
+	v      *viewer
+}
+
+func newToolSelect(v *viewer, label string, button desktop.MouseButton) widget.ToolbarItem {
+	return &toolSelect{label: label, button: button, v: v}
+}
+
+func (t *toolSelect) ToolbarObject() fyne.CanvasObject {
+	s := widget.NewSelect(toolNames, func(name string) {
+		t.v.startEvent()
+		defer t.v.endEvent()
+
+		t.v.tools[t.button] = toolValues[name]
+	})
+	for name, tool := range toolValues {
+		if tool == t.v.tools[t.button] {
+			s.Selected = name
+		}
+	}
+	return container.NewHBox(widget.NewLabel(t.label), s)
+}
+
 func (v *viewer) makeToolbar() *widget.Toolbar {
 	return widget.NewToolbar(
-		newLabelledAction("Open File", theme.FolderOpenIcon(), v.openFile),
-		newLabelledAction("Open Folder", theme.FolderOpenIcon(), v.openFolder),
-		widget.NewToolbarAction(theme.ViewFullScreenIcon(), v.fullScreen))
+		newLabelledAction("Open File", theme.FolderOpenIcon(), v.handle(v.openFile)),
+		newLabelledAction("Open Folder", theme.FolderOpenIcon(), v.handle(v.openFolder)),
+		newLabelledAction("Save State", theme.DocumentSaveIcon(), v.handle(v.savePresentationState)),
+		newLabelledAction("Print", theme.DocumentPrintIcon(), v.handle(v.printToPDF)),
+		newLabelledAction("Tags", theme.ListIcon(), v.handle(v.showTags)),
+		widget.NewToolbarAction(theme.ViewFullScreenIcon(), v.fullScreen),
+		widget.NewToolbarSeparator(),
+		newToolSelect(v, "Left", desktop.MouseButtonPrimary),
+		newToolSelect(v, "Middle", desktop.MouseButtonTertiary),
+		newToolSelect(v, "Right", desktop.MouseButtonSecondary),
+		widget.NewToolbarAction(theme.ZoomFitIcon(), v.handle(func() {
+			v.active.resetView()
+		})),
+		newLabelledAction("Rotate", theme.ViewRefreshIcon(), v.handle(func() {
+			v.rotate(90)
+		})),
+		newLabelledAction("Flip H", theme.MoreHorizontalIcon(), v.handle(v.flipHorizontal)),
+		newLabelledAction("Flip V", theme.MoreVerticalIcon(), v.handle(v.flipVertical)),
+		newLabelledAction("Invert", theme.ColorPaletteIcon(), v.handle(func() {
+			v.active.toggleInverse()
+		})),
+		widget.NewToolbarAction(theme.DeleteIcon(), v.handle(func() {
+			v.active.clearMeasurements()
+		})))
 }
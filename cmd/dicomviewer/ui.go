@@ -4,42 +4,54 @@ import (
 	"fmt"
 	"strconv"
 
-	"github.com/fynelabs/dicomgraphics"
-
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+
+	"github.com/fynelabs/dicomgraphics"
 )
 
 var (
-	presetNames = []string{
-		"Abdomen",
-		"Bone",
-		"Brain",
-		"Lungs",
-		"Mediastinum",
+	layoutNames = []string{
+		"1x1",
+		"1x2",
+		"1x3",
+		"2x2",
 	}
 
-	presetValues = map[string]struct{ level, width int }{
-		"Abdomen":     {40, 400},
-		"Bone":        {400, 1800},
-		"Brain":       {40, 80},
-		"Lungs":       {600, 1500},
-		"Mediastinum": {50, 350},
+	layoutValues = map[string]struct{ rows, cols int }{
+		"1x1": {1, 1},
+		"1x2": {1, 2},
+		"1x3": {1, 3},
+		"2x2": {2, 2},
 	}
 )
 
+// layoutFor returns the smallest layout that can show the given number of viewports.
+func layoutFor(count int) string {
+	for _, name := range layoutNames {
+		val := layoutValues[name]
+		if val.rows*val.cols >= count {
+			return name
+		}
+	}
+
+	return layoutNames[len(layoutNames)-1]
+}
+
 func (v *viewer) fullScreen() {
 	v.win.SetFullScreen(!v.win.FullScreen())
 }
 
 func (v *viewer) openFile() {
 	d := dialog.NewFileOpen(func(f fyne.URIReadCloser, err error) {
+		v.startEvent()
+		defer v.endEvent()
+
 		if f == nil || err != nil {
 			return
 		}
@@ -52,6 +64,9 @@ func (v *viewer) openFile() {
 
 func (v *viewer) openFolder() {
 	d := dialog.NewFolderOpen(func(f fyne.ListableURI, err error) {
+		v.startEvent()
+		defer v.endEvent()
+
 		if f == nil || err != nil {
 			return
 		}
@@ -60,52 +75,139 @@ func (v *viewer) openFolder() {
 	}, v.win)
 	d.Show()
 }
-func (v *viewer) setupForm(dicomImg *dicomgraphics.DICOMImage, img *canvas.Image) fyne.CanvasObject {
-	values := widget.NewForm()
+func (v *viewer) setActive(vp *viewport) {
+	if v.active != nil {
+		v.active.setActive(false)
+	}
+	v.active = vp
+	vp.setActive(true)
+
+	v.refreshActive()
+}
+
+// refreshActive updates the side panel to show the details of the active viewport.
+func (v *viewer) refreshActive() {
+	vp := v.active
+	v.refreshWindow()
+	v.frame.SetText(fmt.Sprintf("%d/%d", vp.currentFrame+1, vp.series.Len()))
+	v.player.refresh()
+	v.refreshTags()
+}
+
+func (v *viewer) refreshWindow() {
+	level, width := v.active.dicom.WindowLevel(), v.active.dicom.WindowWidth()
+	v.level.SetText(strconv.Itoa(int(level)))
+	v.width.SetText(strconv.Itoa(int(width)))
+}
+
+func (v *viewer) setLayout(name string) {
+	val, ok := layoutValues[name]
+	if !ok {
+		return
+	}
+
+	count := val.rows * val.cols
+	for len(v.viewports) < count {
+		v.viewports = append(v.viewports, newViewport(v))
+	}
+
+	v.grid.Layout = layout.NewGridLayoutWithColumns(val.cols)
+	v.grid.Objects = nil
+	for _, vp := range v.viewports[:count] {
+		v.grid.Objects = append(v.grid.Objects, vp)
+	}
+	v.grid.Refresh()
+
+	for _, vp := range v.visibleViewports() {
+		if vp == v.active {
+			return
+		}
+	}
+	v.setActive(v.viewports[0])
+}
 
-	v.id = widget.NewLabel("anon")
-	values.Append("ID", v.id)
-	v.name = widget.NewLabel("anon")
-	values.Append("Name", v.name)
-	v.study = widget.NewLabel("ANON")
-	values.Append("Study", v.study)
+func (v *viewer) visibleViewports() []*viewport {
+	return v.viewports[:len(v.grid.Objects)]
+}
 
+func (v *viewer) setupForm() fyne.CanvasObject {
 	v.level = widget.NewEntry()
-	v.level.SetText(fmt.Sprintf("%d", dicomImg.WindowLevel()))
 	v.level.OnChanged = func(val string) {
-		l, _ := strconv.Atoi(val)
-		dicomImg.SetWindowLevel(int16(l))
+		v.startEvent()
+		defer v.endEvent()
 
-		canvas.Refresh(img)
+		l, _ := strconv.Atoi(val)
+		v.setWindow(dicomgraphics.ClampInt16(float64(l)), v.active.dicom.WindowWidth())
 	}
 
 	v.width = widget.NewEntry()
-	v.width.SetText(fmt.Sprintf("%d", dicomImg.WindowWidth()))
 	v.width.OnChanged = func(val string) {
-		w, _ := strconv.Atoi(val)
-		dicomImg.SetWindowWidth(int16(w))
+		v.startEvent()
+		defer v.endEvent()
 
-		canvas.Refresh(img)
+		w, _ := strconv.Atoi(val)
+		v.setWindow(v.active.dicom.WindowLevel(), dicomgraphics.ClampInt16(float64(w)))
 	}
 
+	var presetNames []string
+	for _, p := range dicomgraphics.WindowPresets {
+		presetNames = append(presetNames, p.Name)
+	}
 	presets := widget.NewSelect(presetNames, func(name string) {
-		val := presetValues[name]
-		v.level.SetText(strconv.Itoa(val.level))
-		v.width.SetText(strconv.Itoa(val.width))
+		v.startEvent()
+		defer v.endEvent()
+
+		val, _ := dicomgraphics.FindWindowPreset(name)
+		v.level.SetText(strconv.Itoa(int(val.Level)))
+		v.width.SetText(strconv.Itoa(int(val.Width)))
 	})
-	return container.NewVBox(values, widget.NewCard("Window", "", widget.NewForm(
+	return widget.NewCard("Window", "", widget.NewForm(
 		widget.NewFormItem("Level", v.level),
 		widget.NewFormItem("Width", v.width),
-		widget.NewFormItem("Preset", presets))))
+		widget.NewFormItem("Preset", presets)))
 }
 
-func (v *viewer) setupNavigation() []fyne.CanvasObject {
-	next := widget.NewButtonWithIcon("", theme.MoveUpIcon(), func() {
-		v.nextFrame()
+func (v *viewer) setupLayout() fyne.CanvasObject {
+	v.layout = widget.NewSelect(layoutNames, func(name string) {
+		v.startEvent()
+		defer v.endEvent()
+
+		v.setLayout(name)
 	})
-	prev := widget.NewButtonWithIcon("", theme.MoveDownIcon(), func() {
-		v.previousFrame()
+
+	scroll := widget.NewCheck("Sync slices", func(on bool) {
+		v.startEvent()
+		defer v.endEvent()
+
+		v.syncScroll = on
+	})
+	window := widget.NewCheck("Sync window", func(on bool) {
+		v.startEvent()
+		defer v.endEvent()
+
+		v.syncWindow = on
+		if on {
+			v.setWindow(v.active.dicom.WindowLevel(), v.active.dicom.WindowWidth())
+		}
 	})
+	return widget.NewCard("Layout", "", container.NewVBox(v.layout, scroll, window))
+}
+
+func (v *viewer) setupDisplay() fyne.CanvasObject {
+	v.overlays = widget.NewCheck("Overlays", v.handleCheck(v.setShowOverlays))
+	v.overlays.Checked = v.showOverlays
+	v.cornerText = widget.NewCheck("Corner text", v.handleCheck(v.setShowCorners))
+	v.cornerText.Checked = v.showCorners
+	corners := widget.NewButtonWithIcon("", theme.SettingsIcon(), v.handle(v.editCorners))
+	v.scaleBar = widget.NewCheck("Scale bar", v.handleCheck(v.setShowScaleBar))
+	v.scaleBar.Checked = v.showScaleBar
+	return widget.NewCard("Display", "", container.NewVBox(v.overlays,
+		container.NewBorder(nil, nil, nil, corners, v.cornerText), v.scaleBar))
+}
+
+func (v *viewer) setupNavigation() []fyne.CanvasObject {
+	next := widget.NewButtonWithIcon("", theme.MoveUpIcon(), v.handle(v.nextFrame))
+	prev := widget.NewButtonWithIcon("", theme.MoveDownIcon(), v.handle(v.previousFrame))
 	full := widget.NewButtonWithIcon("Full Screen", theme.ViewFullScreenIcon(), func() {
 		v.fullScreen()
 	})
@@ -115,6 +217,7 @@ func (v *viewer) setupNavigation() []fyne.CanvasObject {
 		container.NewGridWithColumns(1, next, container.NewCenter(
 			widget.NewForm(&widget.FormItem{Text: "Slice", Widget: v.frame})),
 			prev),
+		v.player.makeUI(),
 		layout.NewSpacer(),
 		full,
 	}
@@ -122,18 +225,25 @@ func (v *viewer) setupNavigation() []fyne.CanvasObject {
 
 func makeUI(a fyne.App) *viewer {
 	win := a.NewWindow("DICOM Viewer")
-	dicomImg := dicomgraphics.NewDICOMImage(nil, 40, 380)
-
-	img := canvas.NewImageFromImage(dicomImg)
-	img.FillMode = canvas.ImageFillContain
 
-	view := &viewer{dicom: dicomImg, image: img, win: win}
-	form := view.setupForm(dicomImg, img)
-	items := []fyne.CanvasObject{view.makeToolbar(), form}
+	view := &viewer{win: win, grid: container.NewGridWithColumns(1), tools: defaultTools(), showOverlays: true,
+		showCorners: true, showScaleBar: true}
+	view.loadCornerLayout(a.Preferences())
+	view.player = newPlayer(view)
+	form := view.setupForm()
+	items := []fyne.CanvasObject{view.makeToolbar(), form, view.setupLayout(), view.setupDisplay()}
 	items = append(items, view.setupNavigation()...)
 	bar := container.NewVBox(items...)
 
-	win.SetContent(container.NewBorder(nil, nil, bar, nil, img))
+	view.status = widget.NewLabel("")
+	view.status.TextStyle.Monospace = true
+
+	view.layout.SetSelected(layoutNames[0])
+	view.browser = container.NewVBox()
+	view.browserScroll = container.NewVScroll(view.browser)
+	view.browserScroll.SetMinSize(fyne.NewSize(thumbnailSize+theme.Padding()*6, 0))
+	view.browserScroll.Hide()
+	win.SetContent(container.NewBorder(nil, view.status, bar, view.browserScroll, view.grid))
 	win.Resize(fyne.NewSize(600, 400))
 
 	return view
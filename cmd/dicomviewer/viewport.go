@@ -0,0 +1,461 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/fynelabs/dicomgraphics"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// viewport displays a single series with its own window settings.
+// It responds to the mouse using the tools assigned to each button.
+type viewport struct {
+	widget.BaseWidget
+	dicom        *dicomgraphics.DICOMImage
+	series       *dicomgraphics.Series
+	currentFrame int
+	fps          float64
+	image        *canvas.Raster
+	overlay      *fyne.Container
+	border       *canvas.Rectangle
+
+	zoom      float32
+	pan       fyne.Position
+	transform dicomgraphics.Transform
+	dragTool  mouseTool
+	dragStart fyne.Position
+	dragLast  fyne.Position
+	// area is the image region kept filling the viewport, as set by a presentation state, or nil.
+	area *[2]dicomgraphics.Point
+	// state is the presentation state applied to this series, whose shutter replaces that of the images, or nil.
+	state *dicomgraphics.PresentationState
+
+	measurements map[*dicomgraphics.Slice][]*dicomgraphics.Measurement
+	pending      *dicomgraphics.Measurement
+	selected     *dicomgraphics.Measurement
+	editPoint    int
+	graphics     map[*dicomgraphics.Slice][]dicomgraphics.Graphic
+	texts        map[*dicomgraphics.Slice][]dicomgraphics.TextAnnotation
+	// stats holds the statistics of each region measurement, until it is edited.
+	stats map[*dicomgraphics.Measurement]dicomgraphics.Statistics
+
+	study string
+
+	// drawn is what the image raster shows, copied from the fields above when the image is redrawn.
+	// The raster is drawn on its own thread, so it reads this copy rather than the viewport that events change.
+	drawn     drawState
+	drawnLock sync.Mutex
+
+	parent *viewer
+}
+
+// drawState holds the image settings and placement that the raster of a viewport is drawn with.
+type drawState struct {
+	image     dicomgraphics.DICOMImage
+	transform dicomgraphics.Transform
+	zoom      float32
+	pan       fyne.Position
+}
+
+func newViewport(parent *viewer) *viewport {
+	dicomImg := dicomgraphics.NewDICOMImage(nil, 40, 380)
+	border := canvas.NewRectangle(color.Transparent)
+	border.StrokeColor = theme.BackgroundColor()
+	border.StrokeWidth = 2
+
+	vp := &viewport{dicom: dicomImg, series: &dicomgraphics.Series{}, border: border, zoom: 1,
+		fps:   dicomgraphics.DefaultFrameRate,
+		study: "ANON", parent: parent}
+	vp.image = canvas.NewRaster(vp.render)
+	vp.overlay = container.NewWithoutLayout()
+	vp.measurements = make(map[*dicomgraphics.Slice][]*dicomgraphics.Measurement)
+	vp.stats = make(map[*dicomgraphics.Measurement]dicomgraphics.Statistics)
+	vp.graphics = make(map[*dicomgraphics.Slice][]dicomgraphics.Graphic)
+	vp.texts = make(map[*dicomgraphics.Slice][]dicomgraphics.TextAnnotation)
+	vp.drawn = vp.snapshot()
+	vp.ExtendBaseWidget(vp)
+	return vp
+}
+
+func (vp *viewport) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewStack(vp.image, vp.overlay, vp.border))
+}
+
+func (vp *viewport) Resize(size fyne.Size) {
+	vp.BaseWidget.Resize(size)
+	if vp.area != nil {
+		vp.fitArea()
+	}
+	vp.refreshOverlay()
+}
+
+func (vp *viewport) Cursor() desktop.Cursor {
+	return desktop.CrosshairCursor
+}
+
+func (vp *viewport) Tapped(_ *fyne.PointEvent) {
+	vp.parent.startEvent()
+	defer vp.parent.endEvent()
+
+	vp.parent.setActive(vp)
+}
+
+func (vp *viewport) MouseDown(ev *desktop.MouseEvent) {
+	vp.parent.startEvent()
+	defer vp.parent.endEvent()
+
+	if vp.parent.active != vp {
+		vp.parent.setActive(vp)
+	}
+
+	vp.dragStart = ev.Position
+	vp.dragLast = ev.Position
+	if ev.Button == desktop.MouseButtonPrimary && vp.startEdit(ev.Position) {
+		vp.dragTool = toolEdit
+		return
+	}
+
+	vp.dragTool = vp.parent.tools[ev.Button]
+	if kind, ok := measureKinds[vp.dragTool]; ok {
+		vp.startMeasurement(kind, ev.Position)
+	} else if vp.dragTool == toolText {
+		vp.addText(ev.Position)
+	}
+}
+
+func (vp *viewport) MouseUp(_ *desktop.MouseEvent) {
+	vp.parent.startEvent()
+	defer vp.parent.endEvent()
+
+	if _, ok := measureKinds[vp.dragTool]; ok || vp.dragTool == toolEdit {
+		vp.endMeasurement(vp.dragTool == toolEdit)
+	}
+	vp.dragTool = toolNone
+}
+
+func (vp *viewport) MouseIn(_ *desktop.MouseEvent) {
+}
+
+func (vp *viewport) MouseMoved(ev *desktop.MouseEvent) {
+	vp.parent.startEvent()
+	defer vp.parent.endEvent()
+
+	if vp.dragTool == toolNone || ev.Button == 0 {
+		vp.dragTool = toolNone
+		vp.parent.status.SetText(vp.probe(ev.Position))
+		return
+	}
+
+	delta := ev.Position.Subtract(vp.dragLast)
+	switch vp.dragTool {
+	case toolWindow:
+		level := float64(vp.dicom.WindowLevel()) - math.Trunc(float64(delta.Y*windowSensitivity))
+		width := float64(vp.dicom.WindowWidth()) + math.Trunc(float64(delta.X*windowSensitivity))
+		vp.parent.setWindow(dicomgraphics.ClampInt16(level), dicomgraphics.ClampInt16(math.Max(1, width)))
+		vp.parent.refreshWindow()
+	case toolPan:
+		vp.pan = vp.pan.Add(delta)
+		vp.area = nil
+		vp.refreshImage()
+	case toolZoom:
+		vp.zoomAbout(vp.dragStart, float32(math.Pow(zoomStep, float64(-delta.Y/10))))
+	case toolScroll:
+		steps := int((ev.Position.Y - vp.dragStart.Y) / scrollDragStep)
+		if steps == 0 {
+			return
+		}
+		vp.parent.setFrame(vp.currentFrame - steps)
+		vp.dragStart.Y += float32(steps) * scrollDragStep
+	case toolEdit, toolRuler, toolAngle, toolCobb, toolRectangle, toolEllipse, toolFreehand:
+		vp.moveMeasurement(ev.Position)
+	}
+	vp.dragLast = ev.Position
+}
+
+func (vp *viewport) MouseOut() {
+	vp.parent.startEvent()
+	defer vp.parent.endEvent()
+
+	vp.parent.status.SetText("")
+}
+
+func (vp *viewport) Scrolled(ev *fyne.ScrollEvent) {
+	vp.parent.startEvent()
+	defer vp.parent.endEvent()
+
+	if vp.parent.active != vp {
+		vp.parent.setActive(vp)
+	}
+
+	if vp.parent.ctrlDown {
+		vp.zoomAbout(ev.Position, float32(math.Pow(zoomStep, float64(ev.Scrolled.DY/10))))
+		return
+	}
+
+	if ev.Scrolled.DY > 0 {
+		vp.parent.previousFrame()
+	} else if ev.Scrolled.DY < 0 {
+		vp.parent.nextFrame()
+	}
+}
+
+// probe describes the pixel under the given position, or returns "" if it is outside the image.
+func (vp *viewport) probe(pos fyne.Position) string {
+	x, y := vp.imagePosition(pos)
+	col, row := int(math.Floor(x)), int(math.Floor(y))
+	stored, ok := vp.dicom.StoredValue(col, row)
+	if !ok {
+		return ""
+	}
+
+	value, _ := vp.dicom.ModalityValue(col, row)
+	slice := vp.series.Slices[vp.currentFrame]
+	text := fmt.Sprintf("Col: %d Row: %d  Stored: %d  Value: %s", col, row, stored,
+		strings.TrimSpace(strconv.FormatFloat(value, 'f', -1, 64)+" "+dicomgraphics.ModalityUnits(slice.Data)))
+	if scale, ok := dicomgraphics.SUVScale(slice.Data); ok {
+		text += fmt.Sprintf("  SUVbw: %.2f", value*scale)
+	}
+	if slice.Plane.Valid() {
+		p := slice.Plane.PatientPosition(float64(col), float64(row))
+		text += fmt.Sprintf("  Patient: (%.1f, %.1f, %.1f) mm", p[0], p[1], p[2])
+	}
+	return text
+}
+
+func (vp *viewport) refreshImage() {
+	vp.redraw()
+	vp.refreshOverlay()
+}
+
+// redraw copies the current image settings for the raster to draw, then refreshes it.
+func (vp *viewport) redraw() {
+	vp.drawnLock.Lock()
+	vp.drawn = vp.snapshot()
+	vp.drawnLock.Unlock()
+	canvas.Refresh(vp.image)
+}
+
+// snapshot returns the current image settings and placement.
+func (vp *viewport) snapshot() drawState {
+	return drawState{image: *vp.dicom, transform: vp.transform, zoom: vp.zoom, pan: vp.pan}
+}
+
+// imagePosition returns the image coordinate shown at the given position in this viewport.
+func (vp *viewport) imagePosition(pos fyne.Position) (float64, float64) {
+	origin, scale := vp.imageTransform(vp.Size())
+	p := dicomgraphics.Point{X: float64((pos.X - origin.X) / scale), Y: float64((pos.Y - origin.Y) / scale)}
+
+	w, h := vp.transform.Size(vp.dicom.Bounds().Dx(), vp.dicom.Bounds().Dy())
+	p = vp.transform.Inverse().Point(p, w, h)
+	return p.X, p.Y
+}
+
+// widgetPosition returns where the given image coordinate is shown in this viewport.
+func (vp *viewport) widgetPosition(x, y float64) fyne.Position {
+	origin, scale := vp.imageTransform(vp.Size())
+	p := vp.transform.Point(dicomgraphics.Point{X: x, Y: y}, vp.dicom.Bounds().Dx(), vp.dicom.Bounds().Dy())
+	return origin.Add(fyne.NewPos(float32(p.X)*scale, float32(p.Y)*scale))
+}
+
+// imageTransform returns where the origin of the rotated and flipped image is drawn
+// and how many units each image pixel covers.
+func (vp *viewport) imageTransform(size fyne.Size) (fyne.Position, float32) {
+	state := vp.snapshot()
+	return state.imageTransform(size)
+}
+
+func (d *drawState) imageTransform(size fyne.Size) (fyne.Position, float32) {
+	b := d.image.Bounds()
+	if b.Empty() {
+		return fyne.NewPos(0, 0), 1
+	}
+
+	dw, dh := d.transform.Size(b.Dx(), b.Dy())
+	w, h := float32(dw), float32(dh)
+	scale := size.Width / w
+	if s := size.Height / h; s < scale {
+		scale = s
+	}
+	scale *= d.zoom
+
+	origin := fyne.NewPos((size.Width-w*scale)/2, (size.Height-h*scale)/2)
+	return origin.Add(d.pan), scale
+}
+
+func (vp *viewport) render(w, h int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.Black, image.Point{}, draw.Src)
+
+	vp.drawnLock.Lock()
+	state := vp.drawn
+	vp.drawnLock.Unlock()
+
+	size := vp.image.Size()
+	b := state.image.Bounds()
+	if b.Empty() || size.Width == 0 {
+		return dst
+	}
+
+	pixScale := float64(w) / float64(size.Width)
+	origin, scale := state.imageTransform(size)
+	// the orientation maps each image axis onto a display axis, found from where unit steps move to
+	o := state.transform.Point(dicomgraphics.Point{}, b.Dx(), b.Dy())
+	dx := state.transform.Point(dicomgraphics.Point{X: 1}, b.Dx(), b.Dy())
+	dy := state.transform.Point(dicomgraphics.Point{Y: 1}, b.Dx(), b.Dy())
+	s := float64(scale) * pixScale
+	s2d := f64.Aff3{
+		(dx.X - o.X) * s, (dy.X - o.X) * s, o.X*s + float64(origin.X)*pixScale,
+		(dx.Y - o.Y) * s, (dy.Y - o.Y) * s, o.Y*s + float64(origin.Y)*pixScale,
+	}
+	draw.NearestNeighbor.Transform(dst, s2d, &state.image, b, draw.Src, nil)
+	return dst
+}
+
+func (vp *viewport) resetView() {
+	vp.zoom = 1
+	vp.pan = fyne.NewPos(0, 0)
+	vp.area = nil
+	vp.refreshImage()
+}
+
+// zoomAbout changes the zoom by a factor whilst keeping the image under pos in place.
+func (vp *viewport) zoomAbout(pos fyne.Position, factor float32) {
+	zoom := vp.zoom * factor
+	if zoom < minZoom || zoom > maxZoom {
+		return
+	}
+
+	x, y := vp.imagePosition(pos)
+	vp.zoom = zoom
+	vp.pan = vp.pan.Add(pos.Subtract(vp.widgetPosition(x, y)))
+	vp.area = nil
+	vp.refreshImage()
+}
+
+func (vp *viewport) loadImage(data *dicom.Dataset) {
+	vp.loadSeries(dicomgraphics.NewSeries(data), data)
+}
+
+func (vp *viewport) loadSeries(series *dicomgraphics.Series, data *dicom.Dataset) {
+	if series.Len() == 0 {
+		fyne.LogError("No images found", nil)
+		return
+	}
+	vp.series = series
+	vp.measurements = make(map[*dicomgraphics.Slice][]*dicomgraphics.Measurement)
+	vp.stats = make(map[*dicomgraphics.Measurement]dicomgraphics.Statistics)
+	vp.graphics = make(map[*dicomgraphics.Slice][]dicomgraphics.Graphic)
+	vp.texts = make(map[*dicomgraphics.Slice][]dicomgraphics.TextAnnotation)
+	vp.pending, vp.selected = nil, nil
+	vp.dicom.SetInverse(false)
+	vp.transform = dicomgraphics.Transform{}
+	vp.state = nil
+	vp.fps = dicomgraphics.FrameRate(data, series.Len())
+	vp.zoom = 1
+	vp.pan = fyne.NewPos(0, 0)
+	vp.area = nil
+
+	if elem, err := data.FindElementByTag(tag.StudyDescription); err == nil {
+		vp.study = fmt.Sprintf("%v", elem.Value)
+	}
+	// without a window in the file the previous one is kept
+	if level, width, ok := dicomgraphics.DefaultWindow(data); ok {
+		vp.dicom.SetWindowLevel(level)
+		vp.dicom.SetWindowWidth(width)
+	}
+
+	vp.setFrame(0)
+}
+
+func (vp *viewport) plane() dicomgraphics.ImagePlane {
+	if vp.series.Len() == 0 {
+		return dicomgraphics.ImagePlane{}
+	}
+
+	return vp.series.Slices[vp.currentFrame].Plane
+}
+
+func (vp *viewport) setActive(active bool) {
+	if active {
+		vp.border.StrokeColor = theme.PrimaryColor()
+	} else {
+		vp.border.StrokeColor = theme.BackgroundColor()
+	}
+	vp.border.Refresh()
+}
+
+func (vp *viewport) setFrame(id int) {
+	count := vp.series.Len()
+	if count == 0 {
+		return
+	}
+	if id > count-1 {
+		id = 0
+	} else if id < 0 {
+		id = count - 1
+	}
+	vp.cancelMeasurement()
+	vp.currentFrame = id
+
+	slice := vp.series.Slices[id]
+	vp.dicom.SetFrame(slice.Frame)
+	vp.dicom.SetRescale(dicomgraphics.Rescale(slice.Data))
+	vp.dicom.SetBitsStored(dicomgraphics.BitsStored(slice.Data))
+	if vp.state != nil && vp.state.References(dicomgraphics.NewImageReference(slice)) {
+		vp.dicom.SetShutter(vp.state.Shutter)
+	} else {
+		vp.dicom.SetShutter(dicomgraphics.ParseFrameShutter(slice.Data, slice.Index, slice.Frame))
+	}
+	vp.refreshOverlays()
+}
+
+// refreshOverlays shows or hides the overlay planes of the current slice and redraws the image.
+func (vp *viewport) refreshOverlays() {
+	slice := vp.currentSlice()
+	if slice == nil {
+		return
+	}
+
+	if vp.parent.showOverlays {
+		vp.dicom.SetOverlays(dicomgraphics.ParseOverlays(slice.Data), slice.Index)
+	} else {
+		vp.dicom.SetOverlays(nil, 0)
+	}
+	vp.refreshImage()
+}
+
+// setTransform changes the rotation and flip of the image, keeping it centred.
+func (vp *viewport) setTransform(t dicomgraphics.Transform) {
+	vp.transform = t
+	vp.pan = fyne.NewPos(0, 0)
+	vp.area = nil
+	vp.refreshImage()
+}
+
+func (vp *viewport) toggleInverse() {
+	vp.dicom.SetInverse(!vp.dicom.Inverse())
+	vp.refreshImage()
+}
+
+func (vp *viewport) setWindow(level, width int16) {
+	vp.dicom.SetWindowLevel(level)
+	vp.dicom.SetWindowWidth(width)
+	vp.refreshImage()
+}
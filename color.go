@@ -0,0 +1,64 @@
+package dicomgraphics
+
+import (
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// ColorFrame draws a slice with three samples per pixel, such as colour Doppler or a fused image, as an RGBA image.
+// Photometric interpretations of RGB and YBR_FULL are supported, for other slices false is returned
+// and they should be drawn with a DICOMImage.
+func ColorFrame(slice *Slice) (*image.RGBA, bool) {
+	f := slice.Frame
+	if f == nil || len(f.Data) == 0 || len(f.Data[0]) < 3 {
+		return nil, false
+	}
+	photometric := strings.TrimSpace(TagString(slice.Data, tag.PhotometricInterpretation))
+	if photometric != "RGB" && photometric != "YBR_FULL" {
+		return nil, false
+	}
+
+	shift := uint(0)
+	if f.BitsPerSample > 8 {
+		shift = uint(f.BitsPerSample - 8)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, f.Cols, f.Rows))
+	for i, px := range f.Data {
+		if i >= f.Cols*f.Rows {
+			break
+		}
+
+		r, g, b := uint8(px[0]>>shift), uint8(px[1]>>shift), uint8(px[2]>>shift)
+		if photometric == "YBR_FULL" {
+			r, g, b = color.YCbCrToRGB(r, g, b)
+		}
+		dst.Pix[i*4], dst.Pix[i*4+1], dst.Pix[i*4+2], dst.Pix[i*4+3] = r, g, b, 0xff
+	}
+	return dst, true
+}
+
+// drawOver paints the shutter and overlay planes of the image over a colour frame of the same size.
+func (d *DICOMImage) drawOver(dst *image.RGBA) {
+	if d.shutter == nil && len(d.overlays) == 0 {
+		return
+	}
+
+	b := dst.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if d.shutter != nil && !d.shutter.Visible(x, y) {
+				dst.Set(x, y, color.Gray16{Y: d.shutter.Value})
+				continue
+			}
+			for _, o := range d.overlays {
+				if o.AlphaAt(x, y).A != 0 {
+					dst.Set(x, y, d.OverlayColor())
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package dicomgraphics
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+)
+
+// CornerMargin is the distance of the four-corner text and orientation markers from the image edge.
+const CornerMargin = 4
+
+// The fields that can be shown in the corners of an image.
+const (
+	CornerPatient  = "patient"
+	CornerID       = "id"
+	CornerDate     = "date"
+	CornerStudy    = "study"
+	CornerSeries   = "series"
+	CornerImage    = "image"
+	CornerLocation = "location"
+	CornerWindow   = "window"
+	CornerZoom     = "zoom"
+)
+
+// Corner indexes of a CornerLayout.
+const (
+	TopLeft = iota
+	TopRight
+	BottomLeft
+	BottomRight
+)
+
+// CornerLayout lists the fields shown in each corner of an image, in the order TopLeft, TopRight, BottomLeft, BottomRight.
+type CornerLayout [4][]string
+
+// DefaultCornerLayout shows demographics at the top and the slice and window at the bottom of an image.
+var DefaultCornerLayout = CornerLayout{
+	{CornerPatient, CornerID},
+	{CornerDate, CornerStudy, CornerSeries},
+	{CornerLocation, CornerImage},
+	{CornerWindow, CornerZoom},
+}
+
+var cornerFields = map[string]bool{CornerPatient: true, CornerID: true, CornerDate: true, CornerStudy: true,
+	CornerSeries: true, CornerImage: true, CornerLocation: true, CornerWindow: true, CornerZoom: true}
+
+// ErrInvalidCornerLayout is returned when a corner layout does not list four corners of known fields.
+var ErrInvalidCornerLayout = errors.New("corner layout must have four corners separated by ';' of " +
+	"patient, id, date, study, series, image, location, window or zoom")
+
+// ErrInvalidCornerField is returned when a list of corner fields includes one that is not known.
+var ErrInvalidCornerField = errors.New("corner fields must be patient, id, date, study, series, image, location, " +
+	"window or zoom")
+
+// CornerInfo holds the values that four-corner text is generated from.
+type CornerInfo struct {
+	Data  *dicom.Dataset
+	Plane ImagePlane
+
+	// Index is the slice shown, starting at 0, of Count slices.
+	Index, Count int
+	Level, Width int16
+	// Zoom is the displayed size of each pixel, 0 hides the zoom field.
+	Zoom float64
+}
+
+// ParseCornerLayout reads a layout of comma separated fields for each corner, with the corners separated by ';'.
+// For example the DefaultCornerLayout is "patient,id;date,study,series;location,image;window,zoom".
+func ParseCornerLayout(s string) (CornerLayout, error) {
+	var layout CornerLayout
+	corners := strings.Split(s, ";")
+	if len(corners) != len(layout) {
+		return layout, ErrInvalidCornerLayout
+	}
+
+	for i, corner := range corners {
+		fields, err := ParseCornerFields(corner)
+		if err != nil {
+			return layout, ErrInvalidCornerLayout
+		}
+		layout[i] = fields
+	}
+	return layout, nil
+}
+
+// ParseCornerFields reads a comma separated list of the fields of one corner, such as "location,image".
+func ParseCornerFields(s string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(s, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		if !cornerFields[field] {
+			return nil, ErrInvalidCornerField
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// String returns the layout in the format read by ParseCornerLayout.
+func (l CornerLayout) String() string {
+	corners := make([]string, len(l))
+	for i, fields := range l {
+		corners[i] = strings.Join(fields, ",")
+	}
+	return strings.Join(corners, ";")
+}
+
+// Text returns the lines of text for each corner, omitting fields that have no value.
+func (l CornerLayout) Text(info CornerInfo) [4][]string {
+	var text [4][]string
+	for i, fields := range l {
+		for _, field := range fields {
+			if line := info.field(field); line != "" {
+				text[i] = append(text[i], line)
+			}
+		}
+	}
+	return text
+}
+
+func (info CornerInfo) field(name string) string {
+	value := func(t tag.Tag) string {
+		if info.Data == nil {
+			return ""
+		}
+		return strings.TrimSpace(TagString(info.Data, t))
+	}
+
+	switch name {
+	case CornerPatient:
+		return formatPersonName(value(tag.PatientName))
+	case CornerID:
+		return value(tag.PatientID)
+	case CornerDate:
+		return formatDate(value(tag.StudyDate))
+	case CornerStudy:
+		return value(tag.StudyDescription)
+	case CornerSeries:
+		return value(tag.SeriesDescription)
+	case CornerImage:
+		if info.Count > 0 {
+			return fmt.Sprintf("Im: %d/%d", info.Index+1, info.Count)
+		}
+	case CornerLocation:
+		if info.Plane.Valid() {
+			return fmt.Sprintf("Loc: %.1f mm", info.Plane.SliceLocation())
+		}
+		if info.Data != nil {
+			if loc := TagFloats(info.Data, tag.SliceLocation); len(loc) > 0 {
+				return fmt.Sprintf("Loc: %.1f mm", loc[0])
+			}
+		}
+	case CornerWindow:
+		return fmt.Sprintf("W: %d L: %d", info.Width, info.Level)
+	case CornerZoom:
+		if info.Zoom > 0 {
+			return fmt.Sprintf("Zoom: %.0f%%", info.Zoom*100)
+		}
+	}
+	return ""
+}
+
+// formatPersonName shows the components of a DICOM person name, such as "Doe^John", separated by spaces.
+func formatPersonName(name string) string {
+	return strings.Join(strings.Fields(strings.Replace(name, "^", " ", -1)), " ")
+}
+
+// formatDate shows a DICOM date of the form YYYYMMDD as YYYY-MM-DD.
+func formatDate(date string) string {
+	if len(date) != 8 {
+		return date
+	}
+	return date[:4] + "-" + date[4:6] + "-" + date[6:]
+}
+
+// DrawCorners burns four-corner text into an image, with orientation markers at the middle of each edge.
+func DrawCorners(dst *image.RGBA, text [4][]string, o Orientation) {
+	face := basicfont.Face7x13
+	b := dst.Bounds()
+	lineHeight := face.Metrics().Height.Ceil()
+	width := func(s string) int {
+		return font.MeasureString(face, asciiLabels.Replace(s)).Ceil()
+	}
+
+	for i, lines := range text {
+		y := b.Min.Y + CornerMargin
+		if i == BottomLeft || i == BottomRight {
+			y = b.Max.Y - CornerMargin - len(lines)*lineHeight
+		}
+		for _, line := range lines {
+			x := b.Min.X + CornerMargin
+			if i == TopRight || i == BottomRight {
+				x = b.Max.X - CornerMargin - width(line)
+			}
+			drawText(dst, line, Point{X: float64(x), Y: float64(y)}, false)
+			y += lineHeight
+		}
+	}
+
+	midX, midY := (b.Min.X+b.Max.X)/2, (b.Min.Y+b.Max.Y-lineHeight)/2
+	drawText(dst, o.Left, Point{X: float64(b.Min.X + CornerMargin), Y: float64(midY)}, false)
+	drawText(dst, o.Right, Point{X: float64(b.Max.X - CornerMargin - width(o.Right)), Y: float64(midY)}, false)
+	drawText(dst, o.Top, Point{X: float64(midX - width(o.Top)/2), Y: float64(b.Min.Y + CornerMargin)}, false)
+	drawText(dst, o.Bottom, Point{X: float64(midX - width(o.Bottom)/2), Y: float64(b.Max.Y - CornerMargin - lineHeight)}, false)
+}
@@ -0,0 +1,252 @@
+package dicomgraphics
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func findElement(elems []*dicom.Element, t tag.Tag) *dicom.Element {
+	for _, elem := range elems {
+		if elem.Tag == t {
+			return elem
+		}
+	}
+
+	return nil
+}
+
+func elementStrings(elem *dicom.Element) []string {
+	if elem == nil || elem.Value == nil {
+		return nil
+	}
+
+	switch val := elem.Value.GetValue().(type) {
+	case []string:
+		return val
+	case []int:
+		ret := make([]string, len(val))
+		for i, v := range val {
+			ret[i] = strconv.Itoa(v)
+		}
+		return ret
+	case []float64:
+		ret := make([]string, len(val))
+		for i, v := range val {
+			ret[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		return ret
+	}
+
+	return nil
+}
+
+// elementString returns the first value of a string element without padding, or "" if it is missing.
+func elementString(elem *dicom.Element) string {
+	values := elementStrings(elem)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return strings.TrimSpace(values[0])
+}
+
+func elementFloats(elem *dicom.Element) []float64 {
+	if elem == nil || elem.Value == nil {
+		return nil
+	}
+
+	switch val := elem.Value.GetValue().(type) {
+	case []float64:
+		return val
+	case []int:
+		ret := make([]float64, len(val))
+		for i, v := range val {
+			ret[i] = float64(v)
+		}
+		return ret
+	case []string:
+		var ret []float64
+		for _, s := range val {
+			f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return nil
+			}
+			ret = append(ret, f)
+		}
+		return ret
+	}
+
+	return nil
+}
+
+func sequenceItems(elem *dicom.Element) [][]*dicom.Element {
+	if elem == nil || elem.Value == nil {
+		return nil
+	}
+
+	items, ok := elem.Value.GetValue().([]*dicom.SequenceItemValue)
+	if !ok {
+		return nil
+	}
+	ret := make([][]*dicom.Element, len(items))
+	for i, item := range items {
+		ret[i] = item.GetValue().([]*dicom.Element)
+	}
+	return ret
+}
+
+// nestedElement looks up a tag inside the first item of each sequence in turn.
+func nestedElement(elems []*dicom.Element, path ...tag.Tag) *dicom.Element {
+	for i, t := range path {
+		elem := findElement(elems, t)
+		if elem == nil || i == len(path)-1 {
+			return elem
+		}
+
+		items := sequenceItems(elem)
+		if len(items) == 0 {
+			return nil
+		}
+		elems = items[0]
+	}
+
+	return nil
+}
+
+// TagString returns the value of the given tag as a single string, with multiple values separated by `\`.
+// An empty string is returned if the tag is not present.
+func TagString(data *dicom.Dataset, t tag.Tag) string {
+	return strings.Join(elementStrings(findElement(data.Elements, t)), "\\")
+}
+
+// TagFloats returns the numeric values of the given tag, or nil if it is missing or not numeric.
+func TagFloats(data *dicom.Dataset, t tag.Tag) []float64 {
+	return elementFloats(findElement(data.Elements, t))
+}
+
+// BitsStored returns how many bits of each stored value hold the pixel, and whether the values are signed.
+// If Bits Stored is missing 0 is returned.
+func BitsStored(data *dicom.Dataset) (int, bool) {
+	bits := 0
+	if b := TagFloats(data, tag.BitsStored); len(b) > 0 {
+		bits = int(b[0])
+	}
+	rep := TagFloats(data, tag.PixelRepresentation)
+	return bits, len(rep) > 0 && rep[0] == 1
+}
+
+// Rescale returns the slope and intercept used to convert stored values to modality values.
+// If the dataset does not specify a rescale then the identity of 1 and 0 is returned.
+func Rescale(data *dicom.Dataset) (float64, float64) {
+	slope, intercept := 1.0, 0.0
+	if s := TagFloats(data, tag.RescaleSlope); len(s) > 0 && s[0] != 0 {
+		slope = s[0]
+	}
+	if i := TagFloats(data, tag.RescaleIntercept); len(i) > 0 {
+		intercept = i[0]
+	}
+
+	return slope, intercept
+}
+
+// ModalityUnits returns the units of rescaled pixel values, such as "HU", or "" if unknown.
+func ModalityUnits(data *dicom.Dataset) string {
+	if t := strings.TrimSpace(TagString(data, tag.RescaleType)); t != "" && t != "US" {
+		return t
+	}
+	if strings.TrimSpace(TagString(data, tag.Modality)) == "CT" {
+		return "HU"
+	}
+
+	return strings.TrimSpace(TagString(data, tag.Units))
+}
+
+// SUVScale returns the factor that converts the rescaled values of a PET image, in Bq/ml, to the standardised uptake
+// value normalised by body weight (SUVbw), in g/ml. The injected dose is decayed to the Series Time for images that
+// are decay corrected to the start of the series, as most are. False is returned if the units are not BQML, the
+// images are not decay corrected, or the patient weight or the dose, start time or half life of the
+// radiopharmaceutical are missing.
+func SUVScale(data *dicom.Dataset) (float64, bool) {
+	if strings.TrimSpace(TagString(data, tag.Units)) != "BQML" {
+		return 0, false
+	}
+	info := tag.RadiopharmaceuticalInformationSequence
+	weight := TagFloats(data, tag.PatientWeight)
+	dose := elementFloats(nestedElement(data.Elements, info, tag.RadionuclideTotalDose))
+	halfLife := elementFloats(nestedElement(data.Elements, info, tag.RadionuclideHalfLife))
+	if len(weight) == 0 || weight[0] <= 0 || len(dose) == 0 || dose[0] <= 0 || len(halfLife) == 0 || halfLife[0] <= 0 {
+		return 0, false
+	}
+
+	decayed := dose[0]
+	switch strings.TrimSpace(TagString(data, tag.DecayCorrection)) {
+	case "ADMIN":
+		// the images are corrected to the time of injection, when the whole dose was present
+	case "START":
+		injected := elementString(nestedElement(data.Elements, info, tag.RadiopharmaceuticalStartTime))
+		if dt := elementString(nestedElement(data.Elements, info, tag.RadiopharmaceuticalStartDateTime)); injected == "" &&
+			len(dt) > 8 {
+			injected = dt[8:]
+		}
+		start, ok := parseTime(injected)
+		series, seriesOK := parseTime(TagString(data, tag.SeriesTime))
+		if !ok || !seriesOK {
+			return 0, false
+		}
+		elapsed := series - start
+		if elapsed < 0 {
+			// the series started on the day after the injection
+			elapsed += 24 * 60 * 60
+		}
+		decayed *= math.Pow(2, -elapsed/halfLife[0])
+	default:
+		return 0, false
+	}
+
+	// the weight is in kg, which is 1000 g
+	return weight[0] * 1000 / decayed, true
+}
+
+// parseTime returns the seconds after midnight of a time value, formatted as HHMMSS.FFFFFF with optional minutes,
+// seconds and fraction, or as HH:MM:SS in older files.
+func parseTime(tm string) (float64, bool) {
+	tm = strings.Replace(strings.TrimSpace(tm), ":", "", -1)
+	if len(tm) < 2 {
+		return 0, false
+	}
+
+	fields := []string{tm[:2], "0", "0"}
+	if len(tm) >= 4 {
+		fields[1] = tm[2:4]
+	}
+	if len(tm) > 4 {
+		fields[2] = tm[4:]
+	}
+	hours, errH := strconv.Atoi(fields[0])
+	minutes, errM := strconv.Atoi(fields[1])
+	seconds, errS := strconv.ParseFloat(fields[2], 64)
+	if errH != nil || errM != nil || errS != nil {
+		return 0, false
+	}
+	return float64(hours*60*60+minutes*60) + seconds, true
+}
+
+// DefaultWindow returns the first Window Center and Window Width of a dataset.
+// Values outside of the 16 bit range are clamped to it.
+// False is returned if either is missing or the width is not positive.
+func DefaultWindow(data *dicom.Dataset) (int16, int16, bool) {
+	levels, widths := TagFloats(data, tag.WindowCenter), TagFloats(data, tag.WindowWidth)
+	if len(levels) == 0 || len(widths) == 0 {
+		return 0, 0, false
+	}
+
+	level, width := ClampInt16(levels[0]), ClampInt16(widths[0])
+	if width <= 0 {
+		return 0, 0, false
+	}
+	return level, width, true
+}
@@ -0,0 +1,195 @@
+package dicomgraphics
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/suyashkumar/dicom"
+)
+
+// ListFiles returns the files named by paths, which may be files, directories to walk or glob patterns
+// such as "scans/*/*.dcm". Paths that cannot be read are passed to onError, if it is not nil, and skipped.
+func ListFiles(paths []string, onError func(path string, err error)) []string {
+	if onError == nil {
+		onError = func(string, error) {}
+	}
+
+	var files []string
+	for _, path := range paths {
+		matches := []string{path}
+		if strings.ContainsAny(path, "*?[") {
+			var err error
+			if matches, err = filepath.Glob(path); err != nil {
+				onError(path, err)
+				continue
+			} else if len(matches) == 0 {
+				onError(path, errors.New("no matching files"))
+				continue
+			}
+		}
+
+		for _, match := range matches {
+			err := filepath.Walk(match, func(file string, info os.FileInfo, err error) error {
+				if err != nil {
+					onError(file, err)
+					return nil
+				}
+				if !info.IsDir() {
+					files = append(files, file)
+				}
+				return nil
+			})
+			if err != nil {
+				onError(match, err)
+			}
+		}
+	}
+	return files
+}
+
+// Parallel calls fn with each number from 0 to n-1, running up to workers calls at once.
+// It returns once every call has finished.
+func Parallel(workers, n int, fn func(i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// ReadFiles parses DICOM files on up to workers goroutines, returning the dataset of each file in the same order.
+// Files that cannot be read are passed to onError, if it is not nil, and left as nil.
+func ReadFiles(files []string, workers int, onError func(path string, err error)) []*dicom.Dataset {
+	var mu sync.Mutex
+	datasets := make([]*dicom.Dataset, len(files))
+	Parallel(workers, len(files), func(i int) {
+		data, err := dicom.ParseFile(files[i], nil)
+		if err != nil {
+			if onError != nil {
+				mu.Lock()
+				onError(files[i], err)
+				mu.Unlock()
+			}
+			return
+		}
+		datasets[i] = &data
+	})
+	return datasets
+}
+
+// SeriesSource is a series read from files, with where it was read from so that outputs can be written beside it.
+type SeriesSource struct {
+	// Path is the file of a series held in one multi-frame file, or the folder holding the first image of a series.
+	Path string
+	// Name is the file name without its extension, or the folder name, to fill the {Name} field of output names.
+	Name string
+
+	*Series
+}
+
+// NewSeriesSource returns the frames of a dataset read from a file as a series named after the file.
+func NewSeriesSource(path string, data *dicom.Dataset) *SeriesSource {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return &SeriesSource{Path: path, Name: name, Series: NewSeries(data)}
+}
+
+// GroupSeriesSources sorts the images of files into series, ordered as GroupStudies does, given the datasets
+// read from each file such as by ReadFiles. A series of many files is named after the folder of its first image,
+// the frames of a single file after that file. Files that could not be read, with a nil dataset, are skipped.
+func GroupSeriesSources(files []string, datasets []*dicom.Dataset) []*SeriesSource {
+	paths := make(map[*dicom.Dataset]string)
+	var found []*dicom.Dataset
+	for i, data := range datasets {
+		if data != nil {
+			paths[data] = files[i]
+			found = append(found, data)
+		}
+	}
+
+	var sources []*SeriesSource
+	for _, study := range GroupStudies(found) {
+		for _, series := range study.Series {
+			if series.Len() == 0 {
+				continue
+			}
+
+			first := series.Slices[0].Data
+			path := paths[first]
+			name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			if series.Slices[series.Len()-1].Data != first {
+				path = filepath.Dir(path)
+				name = filepath.Base(path)
+			}
+			sources = append(sources, &SeriesSource{Path: path, Name: name, Series: series.Series})
+		}
+	}
+	return sources
+}
+
+// ConvertSeries reads files and calls convert with the series in them, running up to workers at once.
+// If bySeries is set every file is read first and their images sorted into series, as GroupSeriesSources does,
+// otherwise the frames of each file are converted as that file is read. Files that cannot be read, and errors
+// returned by convert, are passed to onError with the path of the file or series.
+func ConvertSeries(files []string, workers int, bySeries bool, convert func(*SeriesSource) error,
+	onError func(path string, err error)) {
+	var mu sync.Mutex
+	report := func(path string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		onError(path, err)
+	}
+
+	if bySeries {
+		sources := GroupSeriesSources(files, ReadFiles(files, workers, report))
+		Parallel(workers, len(sources), func(i int) {
+			if err := convert(sources[i]); err != nil {
+				report(sources[i].Path, err)
+			}
+		})
+		return
+	}
+	Parallel(workers, len(files), func(i int) {
+		data, err := dicom.ParseFile(files[i], nil)
+		if err == nil {
+			err = convert(NewSeriesSource(files[i], &data))
+		}
+		if err != nil {
+			report(files[i], err)
+		}
+	})
+}
+
+// WriteFile creates a file, and any directories it is in, and writes its contents with write.
+// The file is closed before returning, so a nil error means that it was saved in full.
+func WriteFile(path string, write func(w io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,167 @@
+package dicomgraphics
+
+import (
+	"errors"
+	"image"
+	"strconv"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// DefaultFilmQuality is the JPEG quality that images are stored in a film sheet with.
+const DefaultFilmQuality = 90
+
+// PaperSize is the size of a page in points, of 1/72 inch.
+type PaperSize struct {
+	Name          string
+	Width, Height float64
+}
+
+// PaperSizes lists the pages that film sheets can be printed on, in portrait orientation.
+var PaperSizes = []PaperSize{
+	{Name: "A4", Width: 595.28, Height: 841.89},
+	{Name: "A3", Width: 841.89, Height: 1190.55},
+	{Name: "Letter", Width: 612, Height: 792},
+	{Name: "Legal", Width: 612, Height: 1008},
+}
+
+// ErrInvalidGrid is returned when a grid of images is not of the form "3x4".
+var ErrInvalidGrid = errors.New("grid must be columns and rows such as 3x4")
+
+// FindPaperSize returns the paper size with a name, ignoring case.
+func FindPaperSize(name string) (PaperSize, bool) {
+	for _, p := range PaperSizes {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return PaperSize{}, false
+}
+
+// Landscape returns the paper turned so that it is wider than it is tall.
+func (p PaperSize) Landscape() PaperSize {
+	if p.Height > p.Width {
+		p.Width, p.Height = p.Height, p.Width
+	}
+	return p
+}
+
+// ParseGrid reads the columns and rows of a grid, such as "3x4" for three images across and four down.
+func ParseGrid(s string) (int, int, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(s)), "x")
+	if len(parts) != 2 {
+		return 0, 0, ErrInvalidGrid
+	}
+	cols, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+	rows, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err1 != nil || err2 != nil || cols < 1 || rows < 1 {
+		return 0, 0, ErrInvalidGrid
+	}
+	return cols, rows, nil
+}
+
+// FilmSheet describes the pages that EncodeFilmSheet lays images out on.
+type FilmSheet struct {
+	// Columns and Rows are the grid of images on each page.
+	Columns, Rows int
+	Paper         PaperSize
+	// Header lines are printed at the top of each page, the first in bold, and Footer at the bottom
+	// beside the page number.
+	Header []string
+	Footer string
+	// Title is stored in the document information, for the title bar of PDF readers.
+	Title string
+	// Quality is the JPEG quality that images are stored with, 0 uses DefaultFilmQuality.
+	Quality int
+}
+
+// NewFilmSheet returns an A4 sheet with the given grid, and a header and footer of the patient, study
+// and institution of a dataset.
+func NewFilmSheet(data *dicom.Dataset, columns, rows int) FilmSheet {
+	value := func(t tag.Tag) string {
+		return strings.TrimSpace(TagString(data, t))
+	}
+	join := func(fields ...string) string {
+		var values []string
+		for _, f := range fields {
+			if f != "" {
+				values = append(values, f)
+			}
+		}
+		return strings.Join(values, "   ")
+	}
+	labelled := func(label, v string) string {
+		if v == "" {
+			return ""
+		}
+		return label + v
+	}
+
+	patient := formatPersonName(value(tag.PatientName))
+	study := formatDate(value(tag.StudyDate))
+	header := []string{
+		join(patient, labelled("ID: ", value(tag.PatientID)), labelled("DOB: ", formatDate(value(tag.PatientBirthDate))),
+			labelled("Sex: ", value(tag.PatientSex))),
+		join(study, value(tag.StudyDescription), labelled("Accession: ", value(tag.AccessionNumber)),
+			labelled("Series: ", join(value(tag.SeriesNumber), value(tag.SeriesDescription)))),
+	}
+	footer := join(value(tag.InstitutionName),
+		labelled("Referred by ", formatPersonName(value(tag.ReferringPhysicianName))))
+	return FilmSheet{Columns: columns, Rows: rows, Paper: PaperSizes[0], Header: header, Footer: footer,
+		Title: join(patient, study)}
+}
+
+// FilmImageOptions control how RenderFilmImage draws a slice.
+type FilmImageOptions struct {
+	// Level and Width are the window that the slice is drawn at, if Width is 0 the default window of the slice is used.
+	Level, Width int16
+	// State is a presentation state whose window, shutter, displayed area, rotation and annotations are applied,
+	// if not nil.
+	State *PresentationState
+	// Annotate burns orientation markers and the four-corner text of Corners into the image.
+	Annotate bool
+	Corners  CornerLayout
+	// Overlays draws the overlay planes of the slice.
+	Overlays bool
+}
+
+// RenderFilmImage draws a slice for printing, with its shutter, measurements and text burned in.
+// The slice is numbered index, from 0, of count images in the corner text.
+func RenderFilmImage(slice *Slice, index, count int, opts FilmImageOptions) *image.RGBA {
+	level, width := opts.Level, opts.Width
+	if width == 0 {
+		level, width = 40, 380
+		if l, w, ok := DefaultWindow(slice.Data); ok {
+			level, width = l, w
+		}
+	}
+	img := NewDICOMImage(slice.Frame, level, width)
+	img.SetRescale(Rescale(slice.Data))
+	img.SetBitsStored(BitsStored(slice.Data))
+	img.SetShutter(ParseFrameShutter(slice.Data, slice.Index, slice.Frame))
+	if opts.Overlays {
+		img.SetOverlays(ParseOverlays(slice.Data), slice.Index)
+	}
+
+	var dst *image.RGBA
+	display := Transform{}
+	if opts.State != nil {
+		dst = opts.State.Render(img, NewImageReference(slice), PixelCalibration(slice.Data))
+		display = Transform{Rotation: opts.State.Rotation, Flip: opts.State.Flip}
+	} else if rgb, ok := ColorFrame(slice); ok {
+		img.drawOver(rgb)
+		dst = rgb
+	} else {
+		dst = Transform{}.Apply(img)
+	}
+
+	if opts.Annotate {
+		info := CornerInfo{Data: slice.Data, Plane: slice.Plane, Index: index, Count: count,
+			Level: img.WindowLevel(), Width: img.WindowWidth()}
+		orientation, _ := ImageOrientation(slice.Data, slice.Plane)
+		DrawCorners(dst, opts.Corners.Text(info), orientation.Transform(display.Flip, display.Rotation))
+	}
+	return dst
+}
@@ -0,0 +1,51 @@
+package dicomgraphics
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidFrames is returned when a frame selection is not "all", a frame number or a range of frames.
+var ErrInvalidFrames = errors.New("frames must be all, a frame number such as 3 or a range such as 1-10, " +
+	"separated by commas")
+
+// ParseFrames reads a selection of frames from an image with count frames, returning their indices in order.
+// Frames are numbered from 1 as in the DICOM Frame Number, a selection may be "all", a frame such as "3",
+// a range such as "1-10" or a comma separated list of these. A range with no end, such as "5-", runs to the last frame.
+func ParseFrames(s string, count int) ([]int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "all") {
+		frames := make([]int, count)
+		for i := range frames {
+			frames[i] = i
+		}
+		return frames, nil
+	}
+
+	var frames []int
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+		start, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, ErrInvalidFrames
+		}
+		end := start
+		if len(bounds) == 2 {
+			if end = count; strings.TrimSpace(bounds[1]) != "" {
+				if end, err = strconv.Atoi(strings.TrimSpace(bounds[1])); err != nil {
+					return nil, ErrInvalidFrames
+				}
+			}
+		}
+		if start < 1 || end > count || start > end {
+			return nil, fmt.Errorf("frames %s out of range, the image has %d frames", strings.TrimSpace(part), count)
+		}
+
+		for i := start; i <= end; i++ {
+			frames = append(frames, i-1)
+		}
+	}
+	return frames, nil
+}
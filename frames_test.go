@@ -0,0 +1,49 @@
+package dicomgraphics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFrames(t *testing.T) {
+	for _, tt := range []struct {
+		name, in string
+		count    int
+		want     []int
+		wantErr  bool
+	}{
+		{"all", "all", 3, []int{0, 1, 2}, false},
+		{"all upper case", " ALL ", 2, []int{0, 1}, false},
+		{"empty is all", "", 2, []int{0, 1}, false},
+		{"single frame", "2", 3, []int{1}, false},
+		{"range", "2-4", 5, []int{1, 2, 3}, false},
+		{"open range", "3-", 4, []int{2, 3}, false},
+		{"single frame range", "2-2", 3, []int{1}, false},
+		{"list", "1, 3-4 ,2", 4, []int{0, 2, 3, 1}, false},
+		{"spaces in range", " 1 - 2 ", 3, []int{0, 1}, false},
+		{"reversed range", "4-2", 5, nil, true},
+		{"frame zero", "0", 3, nil, true},
+		{"past last frame", "4", 3, nil, true},
+		{"range past last frame", "2-5", 3, nil, true},
+		{"no frames", "1", 0, nil, true},
+		{"negative", "-1", 3, nil, true},
+		{"not a number", "a", 3, nil, true},
+		{"invalid end", "1-b", 3, nil, true},
+		{"empty part", "1,,2", 3, nil, true},
+		{"two dashes", "1-2-3", 3, nil, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFrames(tt.in, tt.count)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFrames(%q, %d) error = %v, want error %v", tt.in, tt.count, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFrames(%q, %d) = %v, want %v", tt.in, tt.count, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseFrames("x", 3); err != ErrInvalidFrames {
+		t.Errorf("ParseFrames() error = %v, want %v", err, ErrInvalidFrames)
+	}
+}
@@ -3,6 +3,7 @@ package dicomgraphics
 import (
 	"image"
 	"image/color"
+	"math"
 
 	"github.com/suyashkumar/dicom/pkg/frame"
 )
@@ -11,6 +12,15 @@ type DICOMImage struct {
 	level int16
 	width int16
 
+	slope, intercept float64
+	bitsStored       int
+	signed           bool
+
+	inverse      bool
+	shutter      *Shutter
+	overlays     []*image.Alpha
+	overlayColor color.Color
+
 	frame *frame.NativeFrame
 }
 
@@ -34,7 +44,136 @@ func (d *DICOMImage) SetWindowWidth(width int16) {
 	d.width = width
 }
 
+// Rescale returns the slope and intercept that convert stored values to modality values.
+func (d *DICOMImage) Rescale() (float64, float64) {
+	return d.slope, d.intercept
+}
+
+// SetRescale sets the slope and intercept that convert stored values to modality values, such as HU.
+// The window is applied to the rescaled values.
+func (d *DICOMImage) SetRescale(slope, intercept float64) {
+	d.slope = slope
+	d.intercept = intercept
+}
+
+// SetBitsStored sets how many of the low bits of each stored value hold the pixel, and whether it is signed.
+// Higher bits, such as those used by embedded overlays, are ignored. If bits is 0 the values are read as signed 16 bit.
+func (d *DICOMImage) SetBitsStored(bits int, signed bool) {
+	d.bitsStored = bits
+	d.signed = signed
+}
+
+// Inverse returns true if the image is displayed with the minimum value as white.
+func (d *DICOMImage) Inverse() bool {
+	return d.inverse
+}
+
+// SetInverse sets whether the windowed values are inverted, as with a Presentation LUT Shape of INVERSE.
+func (d *DICOMImage) SetInverse(inverse bool) {
+	d.inverse = inverse
+}
+
+// Shutter returns the display shutter of this image, or nil if there is none.
+func (d *DICOMImage) Shutter() *Shutter {
+	return d.shutter
+}
+
+// SetShutter sets the shutter that hides parts of the image, or nil to show all of it.
+func (d *DICOMImage) SetShutter(s *Shutter) {
+	d.shutter = s
+}
+
+// SetOverlays decodes the overlay planes for a frame of the image, starting at 0, to draw over the pixels.
+// The image frame should be set first, as it is needed for embedded overlays. Nil removes the overlays.
+func (d *DICOMImage) SetOverlays(overlays []*Overlay, frameIndex int) {
+	d.overlays = nil
+	for _, o := range overlays {
+		if mask := o.Mask(frameIndex, d.frame); mask != nil {
+			d.overlays = append(d.overlays, mask)
+		}
+	}
+}
+
+// OverlayColor returns the colour that overlay planes are drawn in.
+func (d *DICOMImage) OverlayColor() color.Color {
+	if d.overlayColor == nil {
+		return DefaultOverlayColor
+	}
+	return d.overlayColor
+}
+
+// SetOverlayColor sets the colour that overlay planes are drawn in.
+func (d *DICOMImage) SetOverlayColor(c color.Color) {
+	d.overlayColor = c
+}
+
+// StoredValue returns the raw pixel value at the given image coordinate.
+// If the coordinate is outside of the image the returned bool is false.
+func (d *DICOMImage) StoredValue(x, y int) (int, bool) {
+	if d.frame == nil || x < 0 || y < 0 || x >= d.frame.Cols || y >= d.frame.Rows {
+		return 0, false
+	}
+
+	i := y*d.frame.Cols + x
+	if i >= len(d.frame.Data) {
+		return 0, false
+	}
+	val := d.frame.Data[i][0]
+	if d.bitsStored <= 0 || d.bitsStored >= 32 {
+		return int(int16(val)), true
+	}
+
+	val &= 1<<uint(d.bitsStored) - 1
+	if d.signed && val&(1<<uint(d.bitsStored-1)) != 0 {
+		val -= 1 << uint(d.bitsStored)
+	}
+	return val, true
+}
+
+// ModalityValue returns the rescaled pixel value at the given image coordinate.
+// If the coordinate is outside of the image the returned bool is false.
+func (d *DICOMImage) ModalityValue(x, y int) (float64, bool) {
+	raw, ok := d.StoredValue(x, y)
+	if !ok {
+		return 0, false
+	}
+
+	return float64(raw)*d.slope + d.intercept, true
+}
+
+// ValueOffset is added to signed values written to 16 bit images, so that -32768 is stored as 0.
+const ValueOffset = 32768
+
+// Values returns the pixel values of the frame as a 16 bit greyscale image, ignoring the window, shutter and overlays.
+// Unsigned stored values are written unchanged and signed values have ValueOffset added.
+// If rescaled is set then the modality values (such as Hounsfield units) are written instead,
+// rounded to whole numbers and offset by ValueOffset, with values outside of the 16 bit range clamped.
+func (d *DICOMImage) Values(rescaled bool) *image.Gray16 {
+	b := d.Bounds()
+	dst := image.NewGray16(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			var val float64
+			if rescaled {
+				val, _ = d.ModalityValue(x, y)
+				val = math.Round(val) + ValueOffset
+			} else {
+				raw, _ := d.StoredValue(x, y)
+				val = float64(raw)
+				if d.signed || d.bitsStored <= 0 || d.bitsStored >= 32 {
+					val += ValueOffset
+				}
+			}
+			dst.SetGray16(x, y, color.Gray16{Y: uint16(math.Max(0, math.Min(0xffff, val)))})
+		}
+	}
+	return dst
+}
+
 func (d *DICOMImage) ColorModel() color.Model {
+	if len(d.overlays) > 0 {
+		return color.RGBA64Model
+	}
 	return color.Gray16Model
 }
 
@@ -50,26 +189,35 @@ func (d *DICOMImage) At(x, y int) color.Color {
 	if d.frame == nil {
 		return color.Gray16{Y: 0}
 	}
-	windowMin := d.level - d.width/2
-	windowMax := windowMin + d.width
+	if d.shutter != nil && !d.shutter.Visible(x, y) {
+		return color.Gray16{Y: d.shutter.Value}
+	}
+	for _, o := range d.overlays {
+		if o.AlphaAt(x, y).A != 0 {
+			return d.OverlayColor()
+		}
+	}
+	windowMin := float64(d.level) - float64(d.width)/2
+	windowMax := windowMin + float64(d.width)
 
-	i := y*d.frame.Rows + x
-	if i >= len(d.frame.Data) {
+	val, ok := d.ModalityValue(x, y)
+	if !ok {
 		return color.Black
 	}
 
-	raw := int16(d.frame.Data[i][0])
-
-	if raw < windowMin {
-		return color.Gray16{Y: 0}
-	} else if raw >= windowMax {
-		return color.Gray16{Y: 0xffff}
+	grey := uint16(0)
+	if val >= windowMax {
+		grey = 0xffff
+	} else if val >= windowMin {
+		grey = uint16(float64(0xffff) * (val - windowMin) / float64(d.width))
 	}
 
-	val := float32(raw-windowMin) / float32(d.width)
-	return color.Gray16{Y: uint16(float32(0xffff) * val)}
+	if d.inverse {
+		grey = 0xffff - grey
+	}
+	return color.Gray16{Y: grey}
 }
 
 func NewDICOMImage(frame *frame.NativeFrame, level, width int16) *DICOMImage {
-	return &DICOMImage{frame: frame, width: width, level: level}
+	return &DICOMImage{frame: frame, width: width, level: level, slope: 1}
 }
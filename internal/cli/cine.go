@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/fynelabs/dicomgraphics"
+)
+
+// CineFlags are the drawing options of the command line tools that write cine loops, which Parse reads
+// into dicomgraphics.CineOptions.
+type CineFlags struct {
+	overlays, annotate    bool
+	overlayColor, corners string
+	maxSize               int
+	fps                   float64
+}
+
+// AddCineFlags registers the cine options on a flag set, such as flag.CommandLine.
+func AddCineFlags(flags *flag.FlagSet) *CineFlags {
+	c := &CineFlags{overlays: true, overlayColor: "#ffffff", corners: dicomgraphics.DefaultCornerLayout.String()}
+	flags.BoolVar(&c.overlays, "overlays", c.overlays, "Draw the overlay planes of the image")
+	flags.StringVar(&c.overlayColor, "overlay-color", c.overlayColor, "The colour of overlay planes, as #rrggbb")
+	flags.BoolVar(&c.annotate, "annotate", c.annotate, "Burn orientation markers and four-corner text into each frame")
+	flags.StringVar(&c.corners, "corners", c.corners, "The fields of each corner for -annotate, as comma separated "+
+		"lists for top left, top right, bottom left and bottom right separated by ';'")
+	flags.IntVar(&c.maxSize, "max-size", c.maxSize, "The largest width or height to write, larger images are scaled down")
+	flags.Float64Var(&c.fps, "fps", c.fps, "The frames per second to play at, instead of the timing of the image")
+	return c
+}
+
+// Parse returns the options given once the flags are parsed, or an error if any are not valid.
+func (c *CineFlags) Parse() (dicomgraphics.CineOptions, error) {
+	opts := dicomgraphics.CineOptions{Overlays: c.overlays, Annotate: c.annotate, MaxSize: c.maxSize, FrameRate: c.fps}
+	if c.maxSize < 0 || c.fps < 0 {
+		return opts, errors.New("size and frame rate must not be negative")
+	}
+	var err error
+	if opts.OverlayColor, err = dicomgraphics.ParseColor(c.overlayColor); err != nil {
+		return opts, errors.New("invalid overlay colour " + c.overlayColor)
+	}
+	opts.Corners, err = dicomgraphics.ParseCornerLayout(c.corners)
+	return opts, err
+}
@@ -0,0 +1,92 @@
+// Package cli holds the command line options shared by the tools in cmd.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+
+	"github.com/suyashkumar/dicom"
+
+	"github.com/fynelabs/dicomgraphics"
+)
+
+// WindowFlags are the -level, -width and -preset options of the command line tools,
+// which replace the window stored in each image.
+type WindowFlags struct {
+	// Level and Width are the window given, LevelSet and WidthSet report which of them were.
+	Level, Width       int16
+	LevelSet, WidthSet bool
+
+	flags        *flag.FlagSet
+	level, width int
+	preset       string
+}
+
+// AddWindowFlags registers the window options on a flag set, such as flag.CommandLine.
+// Parse reads them once the flag set has been parsed.
+func AddWindowFlags(flags *flag.FlagSet) *WindowFlags {
+	w := &WindowFlags{flags: flags}
+	flags.IntVar(&w.level, "level", 0, "The window level, replacing the window of the file")
+	flags.IntVar(&w.width, "width", 0, "The window width, replacing the window of the file")
+	flags.StringVar(&w.preset, "preset", "", "A window preset: abdomen, bone, brain, lungs or mediastinum")
+	return w
+}
+
+// Parse reads the window options after the flags are parsed, returning an error for an unknown preset,
+// a width that is not positive or a level or width beyond the 16 bit range of a window.
+// A level or width given with a preset replaces that of the preset.
+func (w *WindowFlags) Parse() error {
+	if w.preset != "" {
+		p, ok := dicomgraphics.FindWindowPreset(w.preset)
+		if !ok {
+			return errors.New("unknown preset " + w.preset)
+		}
+		w.Level, w.Width, w.LevelSet, w.WidthSet = p.Level, p.Width, true, true
+	}
+	var outOfRange string
+	w.flags.Visit(func(f *flag.Flag) {
+		value := w.level
+		switch f.Name {
+		case "level":
+			w.Level, w.LevelSet = int16(w.level), true
+		case "width":
+			w.Width, w.WidthSet = int16(w.width), true
+			value = w.width
+		default:
+			return
+		}
+		if value < math.MinInt16 || value > math.MaxInt16 {
+			outOfRange = f.Name
+		}
+	})
+	if outOfRange != "" {
+		return fmt.Errorf("window %s must be from %d to %d", outOfRange, math.MinInt16, math.MaxInt16)
+	}
+	if w.WidthSet && w.Width < 1 {
+		return errors.New("window width must be positive")
+	}
+	return nil
+}
+
+// Set reports whether a level or width was given, replacing the window of the images.
+func (w *WindowFlags) Set() bool {
+	return w.LevelSet || w.WidthSet
+}
+
+// Window returns the window to draw a dataset at, its default window or level 40 width 380 if it has none,
+// with the level and width given replacing it.
+func (w *WindowFlags) Window(data *dicom.Dataset) (int16, int16) {
+	level, width, ok := dicomgraphics.DefaultWindow(data)
+	if !ok {
+		level, width = 40, 380
+	}
+	if w.LevelSet {
+		level = w.Level
+	}
+	if w.WidthSet {
+		width = w.Width
+	}
+	return level, width
+}
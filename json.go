@@ -0,0 +1,123 @@
+package dicomgraphics
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// JSONAttribute is an element in the DICOM JSON Model described in PS3.18 F.2.
+type JSONAttribute struct {
+	VR           string        `json:"vr"`
+	Value        []interface{} `json:"Value,omitempty"`
+	InlineBinary string        `json:"InlineBinary,omitempty"`
+}
+
+// JSONDataset is a dataset in the DICOM JSON Model, keyed by the tag as eight upper case hex digits.
+// When encoded with encoding/json the attributes are written in tag order.
+type JSONDataset map[string]JSONAttribute
+
+// NewJSONDataset converts a dataset to the DICOM JSON Model, keeping only the given tags if any are passed.
+// Pixel data is not included as it is decoded into frames, only its VR is written.
+func NewJSONDataset(data *dicom.Dataset, tags ...tag.Tag) JSONDataset {
+	if len(tags) == 0 {
+		return jsonElements(data.Elements)
+	}
+
+	var elems []*dicom.Element
+	for _, t := range tags {
+		if elem := findElement(data.Elements, t); elem != nil {
+			elems = append(elems, elem)
+		}
+	}
+	return jsonElements(elems)
+}
+
+// JSONKey returns the key of a tag in the DICOM JSON Model, such as "00100010".
+func JSONKey(t tag.Tag) string {
+	return fmt.Sprintf("%04X%04X", t.Group, t.Element)
+}
+
+func jsonElements(elems []*dicom.Element) JSONDataset {
+	ret := make(JSONDataset, len(elems))
+	for _, elem := range elems {
+		ret[JSONKey(elem.Tag)] = newJSONAttribute(elem)
+	}
+	return ret
+}
+
+func newJSONAttribute(elem *dicom.Element) JSONAttribute {
+	vr := elem.RawValueRepresentation
+	attr := JSONAttribute{VR: vr}
+	if elem.Value == nil {
+		return attr
+	}
+
+	switch val := elem.Value.GetValue().(type) {
+	case []*dicom.SequenceItemValue:
+		for _, item := range sequenceItems(elem) {
+			attr.Value = append(attr.Value, jsonElements(item))
+		}
+	case dicom.PixelDataInfo:
+		// frames are decoded, so there are no bytes to write inline
+	case []byte:
+		if len(val) > 0 {
+			attr.InlineBinary = base64.StdEncoding.EncodeToString(val)
+		}
+	case []int:
+		if vr == "AT" {
+			for i := 0; i+1 < len(val); i += 2 {
+				attr.Value = append(attr.Value, fmt.Sprintf("%04X%04X", val[i], val[i+1]))
+			}
+			break
+		}
+		for _, v := range val {
+			attr.Value = append(attr.Value, v)
+		}
+	case []float64:
+		for _, v := range val {
+			attr.Value = append(attr.Value, v)
+		}
+	case []string:
+		if len(val) == 1 && strings.TrimSpace(val[0]) == "" {
+			break
+		}
+		for _, s := range val {
+			attr.Value = append(attr.Value, jsonString(vr, s))
+		}
+	}
+	return attr
+}
+
+// jsonString converts a string value to its JSON form, which is a number for IS and DS and an object for PN.
+func jsonString(vr, s string) interface{} {
+	s = strings.TrimRight(s, " \x00")
+	if s == "" {
+		return nil
+	}
+
+	switch vr {
+	case "IS":
+		if i, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			return i
+		}
+	case "DS":
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return f
+		}
+	case "PN":
+		groups := strings.Split(s, "=")
+		name := map[string]string{}
+		for i, key := range []string{"Alphabetic", "Ideographic", "Phonetic"} {
+			if i < len(groups) && groups[i] != "" {
+				name[key] = groups[i]
+			}
+		}
+		return name
+	}
+	return s
+}
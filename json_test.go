@@ -0,0 +1,101 @@
+package dicomgraphics
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// testElement returns an element with the VR of its tag in the dictionary, failing the test if it cannot be made.
+func testElement(t *testing.T, tg tag.Tag, value interface{}) *dicom.Element {
+	elem, err := dicom.NewElement(tg, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return elem
+}
+
+func TestNewJSONDataset(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		elem *dicom.Element
+		want string
+	}{
+		{"person name", testElement(t, tag.PatientName, []string{"Doe^Jane"}),
+			`{"00100010":{"vr":"PN","Value":[{"Alphabetic":"Doe^Jane"}]}}`},
+		{"person name groups", testElement(t, tag.PatientName, []string{"Yamada^Tarou=山田^太郎=やまだ^たろう"}),
+			`{"00100010":{"vr":"PN","Value":[{"Alphabetic":"Yamada^Tarou","Ideographic":"山田^太郎","Phonetic":"やまだ^たろう"}]}}`},
+		{"person name only ideographic", testElement(t, tag.PatientName, []string{"=山田^太郎"}),
+			`{"00100010":{"vr":"PN","Value":[{"Ideographic":"山田^太郎"}]}}`},
+		{"multiple values", testElement(t, tag.ImageType, []string{"ORIGINAL", "PRIMARY"}),
+			`{"00080008":{"vr":"CS","Value":["ORIGINAL","PRIMARY"]}}`},
+		{"padded string", testElement(t, tag.Modality, []string{"CT "}),
+			`{"00080060":{"vr":"CS","Value":["CT"]}}`},
+		{"integer string", testElement(t, tag.SeriesNumber, []string{" 7 "}),
+			`{"00200011":{"vr":"IS","Value":[7]}}`},
+		{"decimal string", testElement(t, tag.PixelSpacing, []string{"0.5", "1.25"}),
+			`{"00280030":{"vr":"DS","Value":[0.5,1.25]}}`},
+		{"unsigned short", testElement(t, tag.Rows, []int{512}),
+			`{"00280010":{"vr":"US","Value":[512]}}`},
+		{"attribute tag", testElement(t, tag.FrameIncrementPointer, []int{0x0018, 0x1063}),
+			`{"00280009":{"vr":"AT","Value":["00181063"]}}`},
+		{"binary", testElement(t, tag.EncapsulatedDocument, []byte{0, 1, 2, 0xff}),
+			`{"00420011":{"vr":"OB","InlineBinary":"AAEC/w=="}}`},
+		{"empty string", testElement(t, tag.AccessionNumber, []string{""}),
+			`{"00080050":{"vr":"SH"}}`},
+		{"blank string", testElement(t, tag.AccessionNumber, []string{"  "}),
+			`{"00080050":{"vr":"SH"}}`},
+		{"no strings", testElement(t, tag.AccessionNumber, []string{}),
+			`{"00080050":{"vr":"SH"}}`},
+		{"empty binary", testElement(t, tag.EncapsulatedDocument, []byte{}),
+			`{"00420011":{"vr":"OB"}}`},
+		{"empty sequence", testElement(t, tag.ReferencedSeriesSequence, [][]*dicom.Element{}),
+			`{"00081115":{"vr":"SQ"}}`},
+		{"no value", &dicom.Element{Tag: tag.StudyDate, RawValueRepresentation: "DA"},
+			`{"00080020":{"vr":"DA"}}`},
+		{"nested sequence", testElement(t, tag.ReferencedSeriesSequence, [][]*dicom.Element{
+			{
+				testElement(t, tag.SeriesInstanceUID, []string{"1.2.3"}),
+				testElement(t, tag.ReferencedImageSequence, [][]*dicom.Element{
+					{testElement(t, tag.ReferencedSOPInstanceUID, []string{"1.2.3.4"})},
+					{testElement(t, tag.ReferencedSOPInstanceUID, []string{"1.2.3.5"}),
+						testElement(t, tag.ReferencedFrameNumber, []string{"2"})},
+				}),
+			},
+			{testElement(t, tag.SeriesInstanceUID, []string{""})},
+		}), `{"00081115":{"vr":"SQ","Value":[` +
+			`{"00081140":{"vr":"SQ","Value":[` +
+			`{"00081155":{"vr":"UI","Value":["1.2.3.4"]}},` +
+			`{"00081155":{"vr":"UI","Value":["1.2.3.5"]},"00081160":{"vr":"IS","Value":[2]}}]},` +
+			`"0020000E":{"vr":"UI","Value":["1.2.3"]}},` +
+			`{"0020000E":{"vr":"UI"}}]}}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(NewJSONDataset(&dicom.Dataset{Elements: []*dicom.Element{tt.elem}}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("NewJSONDataset() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewJSONDatasetTags(t *testing.T) {
+	data := &dicom.Dataset{Elements: []*dicom.Element{
+		testElement(t, tag.PatientName, []string{"Doe^Jane"}),
+		testElement(t, tag.PatientID, []string{"12345"}),
+		testElement(t, tag.Modality, []string{"MR"}),
+	}}
+
+	got, err := json.Marshal(NewJSONDataset(data, tag.Modality, tag.PatientID, tag.StudyDate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"00080060":{"vr":"CS","Value":["MR"]},"00100020":{"vr":"LO","Value":["12345"]}}`; string(got) != want {
+		t.Errorf("NewJSONDataset() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,34 @@
+package dicomgraphics
+
+import (
+	"errors"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// KeyObjectSelectionDocumentStorage is the SOP Class UID of a key object selection (key images) document.
+const KeyObjectSelectionDocumentStorage = "1.2.840.10008.5.1.4.1.1.88.59"
+
+// ErrNotKeyObjectSelection is returned when key images are read from a dataset that is not a key object selection.
+var ErrNotKeyObjectSelection = errors.New("dataset is not a key object selection document")
+
+// ParseKeyImages returns the images, or frames, selected by a key object selection document, in the order listed.
+func ParseKeyImages(data *dicom.Dataset) ([]ImageReference, error) {
+	if TagString(data, tag.SOPClassUID) != KeyObjectSelectionDocumentStorage {
+		return nil, ErrNotKeyObjectSelection
+	}
+
+	var refs []ImageReference
+	var contentItems func(items [][]*dicom.Element)
+	contentItems = func(items [][]*dicom.Element) {
+		for _, item := range items {
+			for _, image := range sequenceItems(findElement(item, tag.ReferencedSOPSequence)) {
+				refs = append(refs, parseImageReferences(image)...)
+			}
+			contentItems(sequenceItems(findElement(item, tag.ContentSequence)))
+		}
+	}
+	contentItems(sequenceItems(findElement(data.Elements, tag.ContentSequence)))
+	return refs, nil
+}
@@ -0,0 +1,215 @@
+package dicomgraphics
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// ultrasoundUnitsCm is the Physical Units value of an ultrasound region measured in cm.
+const ultrasoundUnitsCm = 3
+
+// Point is a location in image pixel coordinates, where (0, 0) is the top left corner of the first pixel.
+type Point struct {
+	X, Y float64
+}
+
+// Calibration describes the physical size of image pixels.
+type Calibration struct {
+	// RowSpacing and ColumnSpacing are the distances between adjacent rows and columns, in mm.
+	RowSpacing, ColumnSpacing float64
+	// AtDetector is set when the spacing is measured at the detector and not corrected to the patient.
+	AtDetector bool
+}
+
+// PixelCalibration returns the calibration of a dataset from Pixel Spacing or the Sequence of Ultrasound Regions,
+// falling back to Imager Pixel Spacing which is measured at the detector.
+// If none are present then the returned calibration is not valid.
+func PixelCalibration(data *dicom.Dataset) Calibration {
+	spacing := ImagePlanes(data, 1)[0].Spacing
+	if spacing[0] > 0 && spacing[1] > 0 {
+		return Calibration{RowSpacing: spacing[0], ColumnSpacing: spacing[1]}
+	}
+	if cal, ok := ultrasoundCalibration(data); ok {
+		return cal
+	}
+
+	if imager := TagFloats(data, tag.ImagerPixelSpacing); len(imager) == 2 && imager[0] > 0 && imager[1] > 0 {
+		return Calibration{RowSpacing: imager[0], ColumnSpacing: imager[1], AtDetector: true}
+	}
+	return Calibration{}
+}
+
+// ultrasoundCalibration returns the spacing of the first ultrasound region measured in cm in both directions.
+// The regions of an image usually share their spacing, so it is applied to the whole image.
+func ultrasoundCalibration(data *dicom.Dataset) (Calibration, bool) {
+	for _, region := range sequenceItems(findElement(data.Elements, tag.SequenceOfUltrasoundRegions)) {
+		unitsX := elementFloats(findElement(region, tag.PhysicalUnitsXDirection))
+		unitsY := elementFloats(findElement(region, tag.PhysicalUnitsYDirection))
+		if len(unitsX) == 0 || len(unitsY) == 0 || unitsX[0] != ultrasoundUnitsCm || unitsY[0] != ultrasoundUnitsCm {
+			continue
+		}
+
+		deltaX := elementFloats(findElement(region, tag.PhysicalDeltaX))
+		deltaY := elementFloats(findElement(region, tag.PhysicalDeltaY))
+		if len(deltaX) > 0 && len(deltaY) > 0 && deltaX[0] > 0 && deltaY[0] > 0 {
+			return Calibration{RowSpacing: deltaY[0] * 10, ColumnSpacing: deltaX[0] * 10}, true
+		}
+	}
+	return Calibration{}, false
+}
+
+// Valid returns true if the calibration can convert pixels to mm.
+func (c Calibration) Valid() bool {
+	return c.RowSpacing > 0 && c.ColumnSpacing > 0
+}
+
+// Units returns the label for distances measured with this calibration.
+func (c Calibration) Units() string {
+	if !c.Valid() {
+		return "px"
+	} else if c.AtDetector {
+		return "mm (not calibrated at patient)"
+	}
+	return "mm"
+}
+
+// AreaUnits returns the label for areas measured with this calibration.
+func (c Calibration) AreaUnits() string {
+	if !c.Valid() {
+		return "px²"
+	} else if c.AtDetector {
+		return "mm² (not calibrated at patient)"
+	}
+	return "mm²"
+}
+
+// physical converts a point to mm, or leaves it in pixels if the calibration is not valid.
+func (c Calibration) physical(p Point) Point {
+	if !c.Valid() {
+		return p
+	}
+
+	return Point{X: p.X * c.ColumnSpacing, Y: p.Y * c.RowSpacing}
+}
+
+// Distance returns the length between two points in mm, or in pixels if the calibration is not valid.
+func Distance(a, b Point, cal Calibration) float64 {
+	a, b = cal.physical(a), cal.physical(b)
+	return math.Hypot(b.X-a.X, b.Y-a.Y)
+}
+
+// Angle returns the angle, in degrees, between the lines from vertex to a and from vertex to b.
+func Angle(a, vertex, b Point, cal Calibration) float64 {
+	a, vertex, b = cal.physical(a), cal.physical(vertex), cal.physical(b)
+	return angleBetween(a.X-vertex.X, a.Y-vertex.Y, b.X-vertex.X, b.Y-vertex.Y)
+}
+
+// CobbAngle returns the angle, in degrees, between the line through a1 and a2 and the line through b1 and b2.
+// The result is between 0 and 90 degrees, as the lines have no direction.
+func CobbAngle(a1, a2, b1, b2 Point, cal Calibration) float64 {
+	a1, a2, b1, b2 = cal.physical(a1), cal.physical(a2), cal.physical(b1), cal.physical(b2)
+	angle := angleBetween(a2.X-a1.X, a2.Y-a1.Y, b2.X-b1.X, b2.Y-b1.Y)
+	if angle > 90 {
+		angle = 180 - angle
+	}
+	return angle
+}
+
+func angleBetween(x1, y1, x2, y2 float64) float64 {
+	l1, l2 := math.Hypot(x1, y1), math.Hypot(x2, y2)
+	if l1 == 0 || l2 == 0 {
+		return 0
+	}
+
+	cos := (x1*x2 + y1*y2) / (l1 * l2)
+	return math.Acos(math.Max(-1, math.Min(1, cos))) * 180 / math.Pi
+}
+
+// MeasurementKind is the type of a Measurement.
+type MeasurementKind int
+
+const (
+	// MeasureRuler is a line between two points.
+	MeasureRuler MeasurementKind = iota
+	// MeasureAngle is three points, where the second is the vertex.
+	MeasureAngle
+	// MeasureCobb is two lines, each defined by two points.
+	MeasureCobb
+	// MeasureRectangle is a rectangular region between two opposite corners.
+	MeasureRectangle
+	// MeasureEllipse is an elliptical region inside the rectangle between two opposite corners.
+	MeasureEllipse
+	// MeasureFreehand is a closed polygon region through all of its points.
+	MeasureFreehand
+)
+
+// PointCount returns the number of points that make a complete measurement of this kind.
+func (k MeasurementKind) PointCount() int {
+	switch k {
+	case MeasureAngle:
+		return 3
+	case MeasureCobb:
+		return 4
+	case MeasureFreehand:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// Measurement is a geometric measurement drawn on an image.
+type Measurement struct {
+	Kind   MeasurementKind
+	Points []Point
+}
+
+// Complete returns true when all of the points of the measurement have been placed.
+func (m *Measurement) Complete() bool {
+	return len(m.Points) >= m.Kind.PointCount()
+}
+
+// IsRegion returns true if the measurement encloses an area rather than measuring a distance or angle.
+func (m *Measurement) IsRegion() bool {
+	return m.Kind == MeasureRectangle || m.Kind == MeasureEllipse || m.Kind == MeasureFreehand
+}
+
+// Value returns the length of a ruler, or the angle in degrees of an angle or Cobb measurement.
+// For regions the area is returned, in mm² or pixels if the calibration is not valid.
+func (m *Measurement) Value(cal Calibration) float64 {
+	if !m.Complete() {
+		return 0
+	}
+
+	p := m.Points
+	switch m.Kind {
+	case MeasureAngle:
+		return Angle(p[0], p[1], p[2], cal)
+	case MeasureCobb:
+		return CobbAngle(p[0], p[1], p[2], p[3], cal)
+	case MeasureRectangle, MeasureEllipse, MeasureFreehand:
+		return regionArea(m.Kind, p, cal)
+	default:
+		return Distance(p[0], p[1], cal)
+	}
+}
+
+// Label returns the value of the measurement formatted with its units.
+func (m *Measurement) Label(cal Calibration) string {
+	if !m.Complete() {
+		return ""
+	}
+
+	switch m.Kind {
+	case MeasureAngle:
+		return fmt.Sprintf("%.1f°", m.Value(cal))
+	case MeasureCobb:
+		return fmt.Sprintf("Cobb %.1f°", m.Value(cal))
+	case MeasureRectangle, MeasureEllipse, MeasureFreehand:
+		return fmt.Sprintf("Area %.1f %s", m.Value(cal), cal.AreaUnits())
+	default:
+		return fmt.Sprintf("%.1f %s", m.Value(cal), cal.Units())
+	}
+}
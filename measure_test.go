@@ -0,0 +1,127 @@
+package dicomgraphics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+const tolerance = 1e-9
+
+var (
+	isotropic   = Calibration{RowSpacing: 0.5, ColumnSpacing: 0.5}
+	anisotropic = Calibration{RowSpacing: 2, ColumnSpacing: 0.5}
+)
+
+func TestDistance(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		a, b Point
+		cal  Calibration
+		want float64
+	}{
+		{"uncalibrated", Point{0, 0}, Point{3, 4}, Calibration{}, 5},
+		{"isotropic", Point{0, 0}, Point{3, 4}, isotropic, 2.5},
+		{"anisotropic horizontal", Point{1, 1}, Point{5, 1}, anisotropic, 2},
+		{"anisotropic vertical", Point{1, 1}, Point{1, 5}, anisotropic, 8},
+		{"anisotropic diagonal", Point{0, 0}, Point{4, 1}, anisotropic, math.Sqrt(8)},
+		{"same point", Point{2, 2}, Point{2, 2}, isotropic, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Distance(tt.a, tt.b, tt.cal); math.Abs(got-tt.want) > tolerance {
+				t.Errorf("Distance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAngle(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		a, vertex, b Point
+		cal          Calibration
+		want         float64
+	}{
+		{"right angle", Point{10, 0}, Point{0, 0}, Point{0, 10}, Calibration{}, 90},
+		{"straight", Point{-5, 0}, Point{0, 0}, Point{5, 0}, isotropic, 180},
+		{"acute", Point{10, 0}, Point{0, 0}, Point{10, 10}, isotropic, 45},
+		// 4 columns and 1 row are both 2 mm, so the diagonal is at 45 degrees rather than 14
+		{"anisotropic", Point{4, 0}, Point{0, 0}, Point{4, 1}, anisotropic, 45},
+		{"zero length arm", Point{0, 0}, Point{0, 0}, Point{5, 5}, isotropic, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Angle(tt.a, tt.vertex, tt.b, tt.cal); math.Abs(got-tt.want) > tolerance {
+				t.Errorf("Angle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCobbAngle(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		a1, a2, b1, b2 Point
+		cal            Calibration
+		want           float64
+	}{
+		{"parallel", Point{0, 0}, Point{10, 0}, Point{0, 5}, Point{10, 5}, Calibration{}, 0},
+		{"perpendicular", Point{0, 0}, Point{10, 0}, Point{0, 0}, Point{0, 10}, isotropic, 90},
+		{"crossing", Point{0, 0}, Point{10, 10}, Point{0, 10}, Point{10, 10}, isotropic, 45},
+		// lines have no direction, so reversing one does not give the obtuse angle
+		{"reversed line", Point{0, 0}, Point{10, 10}, Point{10, 10}, Point{0, 10}, isotropic, 45},
+		{"anisotropic", Point{0, 0}, Point{4, 0}, Point{0, 0}, Point{4, 1}, anisotropic, 45},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CobbAngle(tt.a1, tt.a2, tt.b1, tt.b2, tt.cal); math.Abs(got-tt.want) > tolerance {
+				t.Errorf("CobbAngle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPixelCalibration(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		tags      map[tag.Tag][]string
+		want      Calibration
+		units     string
+		areaUnits string
+	}{
+		{"none", nil, Calibration{}, "px", "px²"},
+		{"pixel spacing", map[tag.Tag][]string{tag.PixelSpacing: {"0.5", "0.5"}},
+			Calibration{RowSpacing: 0.5, ColumnSpacing: 0.5}, "mm", "mm²"},
+		{"anisotropic pixel spacing", map[tag.Tag][]string{tag.PixelSpacing: {"2", "0.5"}},
+			Calibration{RowSpacing: 2, ColumnSpacing: 0.5}, "mm", "mm²"},
+		{"imager pixel spacing", map[tag.Tag][]string{tag.ImagerPixelSpacing: {"0.2", "0.3"}},
+			Calibration{RowSpacing: 0.2, ColumnSpacing: 0.3, AtDetector: true},
+			"mm (not calibrated at patient)", "mm² (not calibrated at patient)"},
+		{"pixel spacing preferred", map[tag.Tag][]string{tag.PixelSpacing: {"0.5", "0.5"},
+			tag.ImagerPixelSpacing: {"0.2", "0.2"}}, Calibration{RowSpacing: 0.5, ColumnSpacing: 0.5}, "mm", "mm²"},
+		{"invalid imager pixel spacing", map[tag.Tag][]string{tag.ImagerPixelSpacing: {"0", "0.2"}},
+			Calibration{}, "px", "px²"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data := &dicom.Dataset{}
+			for tg, values := range tt.tags {
+				elem, err := dicom.NewElement(tg, values)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data.Elements = append(data.Elements, elem)
+			}
+
+			cal := PixelCalibration(data)
+			if cal != tt.want {
+				t.Errorf("PixelCalibration() = %+v, want %+v", cal, tt.want)
+			}
+			if got := cal.Units(); got != tt.units {
+				t.Errorf("Units() = %q, want %q", got, tt.units)
+			}
+			if got := cal.AreaUnits(); got != tt.areaUnits {
+				t.Errorf("AreaUnits() = %q, want %q", got, tt.areaUnits)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package dicomgraphics
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+
+	"golang.org/x/image/font/basicfont"
+)
+
+// montageSpacing is the gap between the tiles of a montage.
+const montageSpacing = 2
+
+// montageBackground fills the gaps between tiles so that neighbouring images are told apart.
+var montageBackground = color.Gray{Y: 0x40}
+
+// MontageOptions control how RenderMontage lays out the slices of a series.
+type MontageOptions struct {
+	// Columns is the number of tiles in each row, 0 chooses a grid that is close to square.
+	Columns int
+	// Step shows every Step slices, starting with the first. 0 or 1 shows every slice.
+	Step int
+	// TileSize is the largest width or height of a tile, larger slices are scaled down. 0 keeps the slice size.
+	TileSize int
+	// Level and Width are the window that slices are drawn at, if Width is 0 the default window of the first slice is used.
+	Level, Width int16
+	// Labels are the corner fields, such as CornerImage or CornerLocation, drawn at the bottom left of each tile.
+	// If it is nil tiles are labelled with CornerImage, an empty list draws no labels.
+	Labels []string
+	// Header draws the patient, study and series details above the tiles.
+	Header bool
+	// Overlays draws the overlay planes of each slice, in OverlayColor or DefaultOverlayColor if it is nil.
+	Overlays     bool
+	OverlayColor color.Color
+}
+
+// RenderMontage draws every Step slices of a series into a grid of tiles, in a single contact sheet image.
+// Tiles are the size of the first slice scaled to fit TileSize.
+func RenderMontage(slices []*Slice, opts MontageOptions) *image.RGBA {
+	step := opts.Step
+	if step < 1 {
+		step = 1
+	}
+	var shown []int
+	for i := 0; i < len(slices); i += step {
+		shown = append(shown, i)
+	}
+	if len(shown) == 0 {
+		return image.NewRGBA(image.Rectangle{})
+	}
+
+	level, width := opts.Level, opts.Width
+	if width == 0 {
+		level, width = 40, 380
+		if l, w, ok := DefaultWindow(slices[0].Data); ok {
+			level, width = l, w
+		}
+	}
+	cols := opts.Columns
+	if cols < 1 {
+		cols = int(math.Ceil(math.Sqrt(float64(len(shown)))))
+	}
+	rows := (len(shown) + cols - 1) / cols
+	first := slices[0].Frame
+	tileW, tileH, _ := FitSize(first.Cols, first.Rows, opts.TileSize, opts.TileSize)
+
+	face := basicfont.Face7x13
+	lineHeight := face.Metrics().Height.Ceil()
+	var header []string
+	headerHeight := 0
+	if opts.Header {
+		header = montageHeader(slices, len(shown), step, level, width)
+		headerHeight = len(header)*lineHeight + 2*CornerMargin
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, cols*tileW+(cols+1)*montageSpacing,
+		headerHeight+rows*tileH+(rows+1)*montageSpacing))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(montageBackground), image.ZP, draw.Src)
+	if opts.Header {
+		draw.Draw(dst, image.Rect(0, 0, dst.Bounds().Dx(), headerHeight), image.Black, image.ZP, draw.Src)
+		for i, line := range header {
+			drawText(dst, line, Point{X: CornerMargin, Y: float64(CornerMargin + i*lineHeight)}, false)
+		}
+	}
+
+	labels := opts.Labels
+	if labels == nil {
+		labels = []string{CornerImage}
+	}
+	r := newSliceRenderer(level, width, opts.Overlays, opts.OverlayColor)
+	for n, i := range shown {
+		x := montageSpacing + n%cols*(tileW+montageSpacing)
+		y := headerHeight + montageSpacing + n/cols*(tileH+montageSpacing)
+		tile := dst.SubImage(image.Rect(x, y, x+tileW, y+tileH)).(*image.RGBA)
+		draw.Draw(tile, tile.Bounds(), image.Black, image.ZP, draw.Src)
+
+		// slices that are not the size of the first are scaled to fit and centred in their tile
+		src, _ := r.render(slices[i])
+		scaled, _ := ScaleToFit(src, tileW, tileH)
+		b := scaled.Bounds()
+		at := image.Pt(x+(tileW-b.Dx())/2, y+(tileH-b.Dy())/2)
+		draw.Draw(tile, image.Rectangle{Min: at, Max: at.Add(b.Size())}, scaled, b.Min, draw.Src)
+
+		info := CornerInfo{Data: slices[i].Data, Plane: slices[i].Plane, Index: i, Count: len(slices), Level: level,
+			Width: width}
+		var text [4][]string
+		for _, field := range labels {
+			if line := info.field(field); line != "" {
+				text[BottomLeft] = append(text[BottomLeft], line)
+			}
+		}
+		DrawCorners(tile, text, Orientation{})
+	}
+	return dst
+}
+
+// montageHeader returns the lines of study details drawn above a montage.
+func montageHeader(slices []*Slice, shown, step int, level, width int16) []string {
+	info := CornerInfo{Data: slices[0].Data, Level: level, Width: width}
+	join := func(fields ...string) string {
+		var values []string
+		for _, f := range fields {
+			if v := info.field(f); v != "" {
+				values = append(values, v)
+			}
+		}
+		return strings.Join(values, "   ")
+	}
+
+	count := fmt.Sprintf("%d of %d images", shown, len(slices))
+	if step > 1 {
+		count += fmt.Sprintf(", every %d", step)
+	}
+	return []string{
+		join(CornerPatient, CornerID, CornerDate, CornerStudy),
+		join(CornerSeries, CornerWindow) + "   " + count,
+	}
+}
@@ -0,0 +1,148 @@
+package dicomgraphics
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/suyashkumar/dicom"
+)
+
+// unknownName is written in place of fields of a name template that have no value.
+const unknownName = "unknown"
+
+// ErrInvalidNameTemplate is returned when a file name template has a '{' that is not closed.
+var ErrInvalidNameTemplate = errors.New("name template has a '{' without a matching '}'")
+
+// ExpandName fills in the fields of a file name template, such as "{PatientID}/{SeriesNumber}_{SeriesDescription}".
+// Each field is a tag keyword or number, or one of the names in values, optionally followed by a width that
+// numbers are padded to with zeros, as in {InstanceNumber:04}. Field values are sanitised so that they are safe
+// in file names, only the '/' written in the template separates directories. Fields with no value are written as
+// "unknown", so that a name is never empty or hidden, such as ".jpg".
+func ExpandName(template string, data *dicom.Dataset, values map[string]string) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			b.WriteString(template)
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return "", ErrInvalidNameTemplate
+		}
+
+		b.WriteString(template[:start])
+		field := template[start+1 : start+end]
+		template = template[start+end+1:]
+
+		name, width := field, ""
+		if i := strings.IndexByte(field, ':'); i >= 0 {
+			name, width = field[:i], field[i+1:]
+		}
+		value, ok := values[name]
+		if !ok {
+			t, err := ParseTag(name)
+			if err != nil {
+				return "", err
+			}
+			value = strings.TrimSpace(TagString(data, t))
+		}
+		if width != "" {
+			n, err := strconv.Atoi(width)
+			if err != nil || n < 0 {
+				return "", fmt.Errorf("invalid width %q in name template", width)
+			}
+			if i, err := strconv.Atoi(value); err == nil {
+				value = fmt.Sprintf("%0*d", n, i)
+			}
+		}
+		if value = SanitiseFileName(value); value == "" {
+			value = unknownName
+		}
+		b.WriteString(value)
+	}
+
+	return filepath.FromSlash(b.String()), nil
+}
+
+// SanitiseFileName replaces the characters that are not allowed in file names on common systems,
+// such as '/', ':' and control characters, with '_' and trims surrounding spaces and trailing dots.
+func SanitiseFileName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, s)
+	return strings.TrimRight(strings.TrimSpace(s), ". ")
+}
+
+// UniqueNames hands out output file names, numbering any that were already given out so that
+// one output does not overwrite another. It is safe for concurrent use.
+type UniqueNames struct {
+	mu   sync.Mutex
+	used map[string]bool
+}
+
+// Reserve returns the name, or if it was already reserved the name with a number such as "_2" before its extension.
+func (u *UniqueNames) Reserve(name string) string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.used == nil {
+		u.used = make(map[string]bool)
+	}
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	unique := filepath.Clean(name)
+	for i := 2; u.used[unique]; i++ {
+		unique = filepath.Clean(fmt.Sprintf("%s_%d%s", base, i, ext))
+	}
+	u.used[unique] = true
+	return unique
+}
+
+// OutputNames places the files written by the command line tools, expanding a name template in a directory
+// or beside each input and numbering names that were already given out. It is safe for concurrent use.
+type OutputNames struct {
+	// Dir is the directory that files are written to, if it is "" they are written beside their input.
+	Dir      string
+	Template string
+	// Names numbers the names given out, and may be shared by outputs with different templates.
+	// If it is nil the names are numbered apart from those of any other OutputNames.
+	Names *UniqueNames
+
+	names UniqueNames
+}
+
+// Path returns the file to write an output made from an input file or folder to, filling in the fields of the
+// template from a dataset of the input and values.
+func (o *OutputNames) Path(input string, data *dicom.Dataset, values map[string]string) (string, error) {
+	dir := o.Dir
+	if dir == "" {
+		dir = filepath.Dir(input)
+	}
+	name, err := ExpandName(o.Template, data, values)
+	if err != nil {
+		return "", err
+	}
+	names := o.Names
+	if names == nil {
+		names = &o.names
+	}
+	return names.Reserve(filepath.Join(dir, name)), nil
+}
+
+// TemplateExt returns the extension written at the end of a file name template, such as ".png",
+// or "" if there is none. An extension that contains a field, as in "{Name}.{Modality}", is not counted.
+func TemplateExt(template string) string {
+	ext := filepath.Ext(template)
+	if strings.ContainsAny(ext, "{}") {
+		return ""
+	}
+	return ext
+}
@@ -0,0 +1,127 @@
+package dicomgraphics
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestExpandName(t *testing.T) {
+	data := &dicom.Dataset{}
+	for tg, values := range map[tag.Tag][]string{
+		tag.PatientID:         {"12345"},
+		tag.SeriesNumber:      {"7"},
+		tag.SeriesDescription: {"AX T2 / FLAIR"},
+		tag.StudyDescription:  {" .. "},
+	} {
+		elem, err := dicom.NewElement(tg, values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data.Elements = append(data.Elements, elem)
+	}
+
+	for _, tt := range []struct {
+		name, template string
+		values         map[string]string
+		want           string
+		wantErr        bool
+	}{
+		{"plain", "image.jpg", nil, "image.jpg", false},
+		{"keyword", "{PatientID}.jpg", nil, "12345.jpg", false},
+		{"tag number", "{(0020,0011)}.jpg", nil, "7.jpg", false},
+		{"directories", "{PatientID}/{SeriesNumber}", nil, filepath.Join("12345", "7"), false},
+		{"padded", "{SeriesNumber:04}", nil, "0007", false},
+		{"padding ignores text", "{PatientID:08}", map[string]string{"PatientID": "ABC"}, "ABC", false},
+		{"value", "{Name}_{Frame:03}", map[string]string{"Name": "scan", "Frame": "12"}, "scan_012", false},
+		{"value replaces tag", "{PatientID}", map[string]string{"PatientID": "anon"}, "anon", false},
+		{"separator sanitised", "{SeriesDescription}", nil, "AX T2 _ FLAIR", false},
+		{"missing", "{AccessionNumber}.jpg", nil, "unknown.jpg", false},
+		{"only dots", "{StudyDescription}.jpg", nil, "unknown.jpg", false},
+		{"empty value", "{Name}.jpg", map[string]string{"Name": ""}, "unknown.jpg", false},
+		{"unclosed", "{PatientID.jpg", nil, "", true},
+		{"unknown field", "{NotAKeyword}", nil, "", true},
+		{"invalid width", "{SeriesNumber:x}", nil, "", true},
+		{"negative width", "{SeriesNumber:-2}", nil, "", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandName(tt.template, data, tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandName() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ExpandName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitiseFileName(t *testing.T) {
+	for _, tt := range []struct {
+		name, in, want string
+	}{
+		{"unchanged", "CT Head 1", "CT Head 1"},
+		{"separators", `a/b\c`, "a_b_c"},
+		{"reserved", `<>:"|?*`, "_______"},
+		{"control characters", "a\tb\x00c\x7f", "a_b_c_"},
+		{"surrounding spaces", "  name  ", "name"},
+		{"trailing dots", "name. . .", "name"},
+		{"leading dots kept", "..name", "..name"},
+		{"only dots", "...", ""},
+		{"unicode kept", "Müller^Jürgen", "Müller^Jürgen"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitiseFileName(tt.in); got != tt.want {
+				t.Errorf("SanitiseFileName(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUniqueNames(t *testing.T) {
+	var u UniqueNames
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"out/image.jpg", filepath.Join("out", "image.jpg")},
+		{"out/image.jpg", filepath.Join("out", "image_2.jpg")},
+		{"out/./image.jpg", filepath.Join("out", "image_3.jpg")},
+		{"out/image_2.jpg", filepath.Join("out", "image_2_2.jpg")},
+		{"other/image.jpg", filepath.Join("other", "image.jpg")},
+		{"out/image", filepath.Join("out", "image")},
+		{"out/image", filepath.Join("out", "image_2")},
+	} {
+		if got := u.Reserve(tt.in); got != tt.want {
+			t.Errorf("Reserve(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestOutputNames(t *testing.T) {
+	data := &dicom.Dataset{}
+	values := map[string]string{"Name": "scan"}
+
+	beside := OutputNames{Template: "{Name}.png"}
+	if got, _ := beside.Path(filepath.Join("in", "scan.dcm"), data, values); got != filepath.Join("in", "scan.png") {
+		t.Errorf("Path() beside input = %q", got)
+	}
+
+	var shared UniqueNames
+	first := OutputNames{Dir: "out", Template: "{Name}.png", Names: &shared}
+	second := OutputNames{Dir: "out", Template: "{Name}.png", Names: &shared}
+	for _, want := range []string{"scan.png", "scan_2.png"} {
+		if got, _ := first.Path("scan.dcm", data, values); got != filepath.Join("out", want) {
+			t.Errorf("Path() = %q, want %q", got, filepath.Join("out", want))
+		}
+	}
+	if got, _ := second.Path("scan.dcm", data, values); got != filepath.Join("out", "scan_3.png") {
+		t.Errorf("Path() with shared names = %q, want %q", got, filepath.Join("out", "scan_3.png"))
+	}
+
+	invalid := OutputNames{Template: "{Name"}
+	if _, err := invalid.Path("scan.dcm", data, values); err != ErrInvalidNameTemplate {
+		t.Errorf("Path() error = %v, want %v", err, ErrInvalidNameTemplate)
+	}
+}
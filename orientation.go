@@ -0,0 +1,76 @@
+package dicomgraphics
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// minOrientationComponent is the smallest part of a direction cosine included in an orientation label.
+const minOrientationComponent = 0.25
+
+// Orientation holds the patient directions at the edges of a displayed image, such as "A" or "LH".
+type Orientation struct {
+	Left, Right, Top, Bottom string
+}
+
+// ImageOrientation returns the patient directions at the edges of an image.
+// They are computed from the orientation of the plane, or read from Patient Orientation (0020,0020) if that is unknown.
+// False is returned if neither is present.
+func ImageOrientation(data *dicom.Dataset, plane ImagePlane) (Orientation, bool) {
+	if plane.Orientation != [6]float64{} {
+		right := directionLabel(plane.Orientation[:3])
+		bottom := directionLabel(plane.Orientation[3:])
+		return Orientation{Left: oppositeLabel(right), Right: right, Top: oppositeLabel(bottom), Bottom: bottom}, true
+	}
+
+	if data == nil {
+		return Orientation{}, false
+	}
+	labels := elementStrings(findElement(data.Elements, tag.PatientOrientation))
+	if len(labels) != 2 {
+		return Orientation{}, false
+	}
+	right, bottom := strings.TrimSpace(labels[0]), strings.TrimSpace(labels[1])
+	return Orientation{Left: oppositeLabel(right), Right: right, Top: oppositeLabel(bottom), Bottom: bottom}, true
+}
+
+// Transform returns the orientation of the image after it is mirrored horizontally if flip is set,
+// then rotated clockwise by a multiple of 90 degrees.
+func (o Orientation) Transform(flip bool, rotation int) Orientation {
+	if flip {
+		o.Left, o.Right = o.Right, o.Left
+	}
+
+	for r := (rotation%360 + 360) % 360; r > 0; r -= 90 {
+		o = Orientation{Left: o.Bottom, Right: o.Top, Top: o.Left, Bottom: o.Right}
+	}
+	return o
+}
+
+// directionLabel describes a direction in patient coordinates, with the strongest component first.
+func directionLabel(dir []float64) string {
+	axes := [3][2]string{{"R", "L"}, {"A", "P"}, {"F", "H"}}
+	order := []int{0, 1, 2}
+	sort.SliceStable(order, func(i, j int) bool {
+		return math.Abs(dir[order[i]]) > math.Abs(dir[order[j]])
+	})
+
+	label := ""
+	for _, axis := range order {
+		if dir[axis] <= -minOrientationComponent {
+			label += axes[axis][0]
+		} else if dir[axis] >= minOrientationComponent {
+			label += axes[axis][1]
+		}
+	}
+	return label
+}
+
+// oppositeLabel returns the direction label pointing the other way, such as "RA" for "LP".
+func oppositeLabel(label string) string {
+	return strings.NewReplacer("L", "R", "R", "L", "A", "P", "P", "A", "H", "F", "F", "H").Replace(label)
+}
@@ -0,0 +1,214 @@
+package dicomgraphics
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+const (
+	firstOverlayGroup = 0x6000
+	lastOverlayGroup  = 0x601e
+
+	overlayRows          = 0x0010
+	overlayColumns       = 0x0011
+	overlayFrames        = 0x0015
+	overlayDescription   = 0x0022
+	overlayType          = 0x0040
+	overlayOrigin        = 0x0050
+	overlayFrameOrigin   = 0x0051
+	overlayBitsAllocated = 0x0100
+	overlayBitPosition   = 0x0102
+	overlayLabel         = 0x1500
+	overlayData          = 0x3000
+)
+
+// DefaultOverlayColor is the colour used to draw overlay planes if none is set.
+var DefaultOverlayColor color.Color = color.White
+
+// ErrInvalidColor is returned when a colour is not of the form #rrggbb.
+var ErrInvalidColor = errors.New("colour must be of the form #rrggbb")
+
+// ParseColor reads a colour in the form #rrggbb, such as an overlay colour given on the command line.
+func ParseColor(hex string) (color.Color, error) {
+	c := color.RGBA{A: 0xff}
+	if len(hex) != 7 {
+		return nil, ErrInvalidColor
+	}
+	if _, err := fmt.Sscanf(hex, "#%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+		return nil, ErrInvalidColor
+	}
+	return c, nil
+}
+
+// Overlay is a bitmap overlay plane, stored in one of the 60xx groups of a dataset.
+type Overlay struct {
+	// Group is the DICOM group of the overlay, from 0x6000 to 0x601E.
+	Group uint16
+	// Rows and Columns are the size of the overlay bitmap.
+	Rows, Columns int
+	// Origin is the image coordinate of the top left pixel of the overlay.
+	Origin image.Point
+	// Type is "G" for graphics or "R" for a region of interest.
+	Type               string
+	Label, Description string
+
+	// Frames is the number of frames in the overlay, and FirstFrame the image frame it starts at, counting from 1.
+	Frames, FirstFrame int
+	// BitPosition is the bit of each stored value holding an overlay embedded in the pixel data.
+	BitPosition int
+
+	data []byte
+}
+
+// ParseOverlays reads the overlay planes of a dataset, including those embedded in unused bits of the pixel data.
+// An overlay used as a bitmap shutter is not returned, as it is drawn by the Shutter instead.
+func ParseOverlays(data *dicom.Dataset) []*Overlay {
+	var overlays []*Overlay
+	for _, o := range parseOverlays(data) {
+		if !isBitmapShutter(data, o.Group) {
+			overlays = append(overlays, o)
+		}
+	}
+	return overlays
+}
+
+func parseOverlays(data *dicom.Dataset) []*Overlay {
+	groups := make(map[uint16][]*dicom.Element)
+	for _, elem := range data.Elements {
+		if g := elem.Tag.Group; g >= firstOverlayGroup && g <= lastOverlayGroup && g%2 == 0 {
+			groups[g] = append(groups[g], elem)
+		}
+	}
+
+	var overlays []*Overlay
+	for group, elems := range groups {
+		if o := parseOverlay(group, elems); o != nil {
+			overlays = append(overlays, o)
+		}
+	}
+	sort.Slice(overlays, func(i, j int) bool {
+		return overlays[i].Group < overlays[j].Group
+	})
+	return overlays
+}
+
+func parseOverlay(group uint16, elems []*dicom.Element) *Overlay {
+	get := func(element uint16) *dicom.Element {
+		return findElement(elems, tag.Tag{Group: group, Element: element})
+	}
+
+	o := &Overlay{Group: group, Frames: 1, FirstFrame: 1}
+	o.Rows = overlayInt(get(overlayRows), 0, false)
+	o.Columns = overlayInt(get(overlayColumns), 0, false)
+	if o.Rows <= 0 || o.Columns <= 0 {
+		return nil
+	}
+	if origin := get(overlayOrigin); origin != nil {
+		o.Origin = image.Pt(overlayInt(origin, 1, true)-1, overlayInt(origin, 0, true)-1)
+	}
+	if frames := overlayInt(get(overlayFrames), 0, false); frames > 0 {
+		o.Frames = frames
+	}
+	if first := overlayInt(get(overlayFrameOrigin), 0, false); first > 0 {
+		o.FirstFrame = first
+	}
+	o.Type = overlayString(get(overlayType))
+	o.Label = overlayString(get(overlayLabel))
+	o.Description = overlayString(get(overlayDescription))
+	o.BitPosition = overlayInt(get(overlayBitPosition), 0, false)
+
+	if elem := get(overlayData); elem != nil {
+		o.data, _ = elem.Value.GetValue().([]byte)
+	} else if overlayInt(get(overlayBitsAllocated), 0, false) <= 1 {
+		return nil // neither overlay data nor an embedded bit plane
+	}
+	return o
+}
+
+// overlayInt reads a binary value of an overlay element, which is stored as bytes if the VR was not known.
+func overlayInt(elem *dicom.Element, index int, signed bool) int {
+	if elem == nil || elem.Value == nil {
+		return 0
+	}
+
+	switch val := elem.Value.GetValue().(type) {
+	case []int:
+		if index < len(val) {
+			return val[index]
+		}
+	case []byte:
+		if (index+1)*2 <= len(val) {
+			v := binary.LittleEndian.Uint16(val[index*2:])
+			if signed {
+				return int(int16(v))
+			}
+			return int(v)
+		}
+	case []string:
+		if f := elementFloats(elem); index < len(f) {
+			return int(f[index])
+		}
+	}
+	return 0
+}
+
+func overlayString(elem *dicom.Element) string {
+	if elem == nil || elem.Value == nil {
+		return ""
+	}
+
+	if val, ok := elem.Value.GetValue().([]byte); ok {
+		return strings.TrimRight(string(val), " \x00")
+	}
+	return strings.TrimSpace(strings.Join(elementStrings(elem), "\\"))
+}
+
+// Embedded returns true if the overlay is stored in the high bits of the pixel data rather than in Overlay Data.
+func (o *Overlay) Embedded() bool {
+	return o.data == nil
+}
+
+// Mask returns the pixels set by the overlay for a frame of the image, starting at 0, in image coordinates.
+// The pixels of the frame are needed to read embedded overlays.
+// Nil is returned if the overlay does not apply to the frame.
+func (o *Overlay) Mask(frameIndex int, pixels *frame.NativeFrame) *image.Alpha {
+	overlayFrame := 0
+	if o.Frames > 1 {
+		overlayFrame = frameIndex + 1 - o.FirstFrame
+		if overlayFrame < 0 || overlayFrame >= o.Frames {
+			return nil
+		}
+	}
+
+	mask := image.NewAlpha(image.Rectangle{Min: o.Origin, Max: o.Origin.Add(image.Pt(o.Columns, o.Rows))})
+	start := overlayFrame * o.Rows * o.Columns
+	for y := 0; y < o.Rows; y++ {
+		for x := 0; x < o.Columns; x++ {
+			if o.bit(start+y*o.Columns+x, x+o.Origin.X, y+o.Origin.Y, pixels) {
+				mask.SetAlpha(x+o.Origin.X, y+o.Origin.Y, color.Alpha{A: 0xff})
+			}
+		}
+	}
+	return mask
+}
+
+// bit returns whether the overlay is set at index of its data, or at the pixel x, y for embedded overlays.
+func (o *Overlay) bit(index, x, y int, pixels *frame.NativeFrame) bool {
+	if o.data != nil {
+		return index/8 < len(o.data) && o.data[index/8]&(1<<uint(index%8)) != 0
+	}
+
+	if pixels == nil || x < 0 || y < 0 || x >= pixels.Cols || y >= pixels.Rows {
+		return false
+	}
+	return pixels.Data[y*pixels.Cols+x][0]&(1<<uint(o.BitPosition)) != 0
+}
@@ -0,0 +1,222 @@
+package dicomgraphics
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"math"
+	"strings"
+)
+
+// The layout of a film sheet page, in points.
+const (
+	filmMargin   = 36
+	filmGap      = 4
+	filmFontSize = 9
+	filmLeading  = 11
+)
+
+// helveticaWidths are the widths of the printable ASCII characters of the Helvetica font, in 1/1000 of the font size,
+// from its Adobe font metrics. Other characters are measured as the width of a digit.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// pdfWriter writes the numbered objects of a PDF file, recording where each starts for the cross reference table.
+type pdfWriter struct {
+	w       *bufio.Writer
+	offset  int
+	offsets map[int]int
+	err     error
+}
+
+func (p *pdfWriter) write(s string) {
+	if p.err != nil {
+		return
+	}
+	n, err := p.w.WriteString(s)
+	p.offset += n
+	p.err = err
+}
+
+// object writes an object whose body is a dictionary or other value.
+func (p *pdfWriter) object(id int, body string) {
+	p.offsets[id] = p.offset
+	p.write(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", id, body))
+}
+
+// stream writes an object of data with its dictionary, which must not include the length.
+func (p *pdfWriter) stream(id int, dict string, data []byte) {
+	p.offsets[id] = p.offset
+	p.write(fmt.Sprintf("%d 0 obj\n<< %s /Length %d >>\nstream\n", id, dict, len(data)))
+	p.write(string(data))
+	p.write("\nendstream\nendobj\n")
+}
+
+// EncodeFilmSheet writes images as a PDF, laid out in the grid of the sheet with as many pages as are needed.
+// Each image is scaled to fill its cell keeping its aspect ratio, and is stored as a JPEG.
+func EncodeFilmSheet(w io.Writer, images []image.Image, sheet FilmSheet) error {
+	if len(images) == 0 {
+		return errors.New("pdf: no images to print")
+	}
+	if sheet.Columns < 1 || sheet.Rows < 1 {
+		return ErrInvalidGrid
+	}
+	paper := sheet.Paper
+	if paper.Width <= 0 || paper.Height <= 0 {
+		paper = PaperSizes[0]
+	}
+	quality := sheet.Quality
+	if quality == 0 {
+		quality = DefaultFilmQuality
+	}
+
+	perPage := sheet.Columns * sheet.Rows
+	pages := (len(images) + perPage - 1) / perPage
+	// objects 1 to 5 are the catalog, page tree, fonts and information, followed by each page and its contents,
+	// then the images
+	const catalog, pageTree, font, boldFont, info = 1, 2, 3, 4, 5
+	pageID := func(page int) int {
+		return 6 + 2*page
+	}
+	imageID := func(i int) int {
+		return 6 + 2*pages + i
+	}
+
+	p := &pdfWriter{w: bufio.NewWriter(w), offsets: make(map[int]int)}
+	p.write("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	p.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pageTree))
+	kids := make([]string, pages)
+	for i := range kids {
+		kids[i] = fmt.Sprintf("%d 0 R", pageID(i))
+	}
+	p.object(pageTree, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 %.2f %.2f] >>",
+		strings.Join(kids, " "), pages, paper.Width, paper.Height))
+	p.object(font, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	p.object(boldFont, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	p.object(info, fmt.Sprintf("<< /Title %s /Producer (dicomgraphics) >>", pdfString(sheet.Title)))
+
+	// the grid fills the page between the rules under the header and over the footer
+	top := paper.Height - filmMargin - float64(len(sheet.Header))*filmLeading - filmGap
+	bottom := float64(filmMargin + filmLeading + filmGap)
+	cellW := (paper.Width - 2*filmMargin - float64(sheet.Columns-1)*filmGap) / float64(sheet.Columns)
+	cellH := (top - bottom - 2*filmGap - float64(sheet.Rows-1)*filmGap) / float64(sheet.Rows)
+	for page := 0; page < pages; page++ {
+		var content bytes.Buffer
+		var xobjects []string
+		for cell := 0; cell < perPage; cell++ {
+			i := page*perPage + cell
+			if i >= len(images) {
+				break
+			}
+			b := images[i].Bounds()
+			if b.Empty() {
+				continue
+			}
+			scale := math.Min(cellW/float64(b.Dx()), cellH/float64(b.Dy()))
+			w, h := float64(b.Dx())*scale, float64(b.Dy())*scale
+			x := filmMargin + float64(cell%sheet.Columns)*(cellW+filmGap) + (cellW-w)/2
+			y := top - filmGap - float64(cell/sheet.Columns)*(cellH+filmGap) - (cellH+h)/2
+			fmt.Fprintf(&content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, x, y, i)
+			xobjects = append(xobjects, fmt.Sprintf("/Im%d %d 0 R", i, imageID(i)))
+		}
+
+		for i, line := range sheet.Header {
+			f := "F1"
+			if i == 0 {
+				f = "F2"
+			}
+			pdfText(&content, f, filmMargin, paper.Height-filmMargin-filmFontSize-float64(i)*filmLeading, line)
+		}
+		number := fmt.Sprintf("Page %d of %d", page+1, pages)
+		pdfText(&content, "F1", filmMargin, filmMargin, sheet.Footer)
+		pdfText(&content, "F1", paper.Width-filmMargin-textWidth(number, filmFontSize), filmMargin, number)
+		fmt.Fprintf(&content, "0.5 w %.2f %.2f m %.2f %.2f l S\n", float64(filmMargin), top,
+			paper.Width-filmMargin, top)
+		fmt.Fprintf(&content, "%.2f %.2f m %.2f %.2f l S\n", float64(filmMargin), bottom,
+			paper.Width-filmMargin, bottom)
+
+		p.object(pageID(page), fmt.Sprintf("<< /Type /Page /Parent %d 0 R /Contents %d 0 R "+
+			"/Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> /XObject << %s >> >> >>",
+			pageTree, pageID(page)+1, font, boldFont, strings.Join(xobjects, " ")))
+		p.stream(pageID(page)+1, "", content.Bytes())
+	}
+
+	for i, img := range images {
+		grey := isGrey(img)
+		var data bytes.Buffer
+		if err := jpeg.Encode(&data, eightBitImage(img, grey), &jpeg.Options{Quality: quality}); err != nil {
+			return err
+		}
+		space := "/DeviceRGB"
+		if grey {
+			space = "/DeviceGray"
+		}
+		b := img.Bounds()
+		p.stream(imageID(i), fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s "+
+			"/BitsPerComponent 8 /Filter /DCTDecode", b.Dx(), b.Dy(), space), data.Bytes())
+	}
+
+	objects := imageID(len(images))
+	xref := p.offset
+	p.write(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", objects))
+	for id := 1; id < objects; id++ {
+		p.write(fmt.Sprintf("%010d 00000 n \n", p.offsets[id]))
+	}
+	p.write(fmt.Sprintf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		objects, catalog, info, xref))
+	if p.err != nil {
+		return p.err
+	}
+	return p.w.Flush()
+}
+
+// pdfText adds a line of text to a page content stream, with its baseline starting at x, y.
+func pdfText(content *bytes.Buffer, font string, x, y float64, text string) {
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(content, "BT /%s %d Tf %.2f %.2f Td %s Tj ET\n", font, filmFontSize, x, y, pdfString(text))
+}
+
+// pdfString returns text as a PDF string literal in the Windows-1252 encoding of the built in fonts.
+// Characters outside Latin-1 are replaced with '?'.
+func pdfString(text string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r < ' ' || r > 0xff || (r >= 0x7f && r < 0xa0):
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// textWidth returns the width of text in the Helvetica font of the given size.
+func textWidth(text string, size float64) float64 {
+	width := 0
+	for _, r := range text {
+		if r >= ' ' && r <= '~' {
+			width += helveticaWidths[r-' ']
+		} else {
+			width += helveticaWidths['0'-' ']
+		}
+	}
+	return float64(width) * size / 1000
+}
@@ -0,0 +1,101 @@
+package dicomgraphics
+
+import (
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// ImagePlane describes where a frame is located in the patient coordinate system.
+type ImagePlane struct {
+	// Position is the patient coordinate of the centre of the first transmitted pixel, in mm.
+	Position [3]float64
+	// Orientation holds the direction cosines of the first row and then the first column.
+	Orientation [6]float64
+	// Spacing is the distance between row centres and then column centres, in mm.
+	Spacing [2]float64
+	// FrameOfReference is the UID that relates planes sharing the same patient coordinate system.
+	FrameOfReference string
+
+	valid bool
+}
+
+// Valid returns true if position and orientation were found for this plane.
+func (p ImagePlane) Valid() bool {
+	return p.valid
+}
+
+// Normal returns the unit vector perpendicular to the plane.
+func (p ImagePlane) Normal() [3]float64 {
+	r, c := p.Orientation[:3], p.Orientation[3:]
+	return [3]float64{
+		r[1]*c[2] - r[2]*c[1],
+		r[2]*c[0] - r[0]*c[2],
+		r[0]*c[1] - r[1]*c[0],
+	}
+}
+
+// SliceLocation returns the distance of the plane along its normal, suitable for ordering and matching slices.
+func (p ImagePlane) SliceLocation() float64 {
+	n := p.Normal()
+	return p.Position[0]*n[0] + p.Position[1]*n[1] + p.Position[2]*n[2]
+}
+
+// PatientPosition returns the patient coordinate, in mm, of the pixel at the given column and row.
+func (p ImagePlane) PatientPosition(col, row float64) [3]float64 {
+	var ret [3]float64
+	for i := 0; i < 3; i++ {
+		ret[i] = p.Position[i] + p.Orientation[i]*p.Spacing[1]*col + p.Orientation[i+3]*p.Spacing[0]*row
+	}
+	return ret
+}
+
+// ImagePlanes returns the plane of each frame in the dataset.
+// Enhanced multi-frame objects are read from their functional groups, other objects share a single plane.
+func ImagePlanes(data *dicom.Dataset, frames int) []ImagePlane {
+	base := ImagePlane{FrameOfReference: TagString(data, tag.FrameOfReferenceUID)}
+	base.setSpacing(TagFloats(data, tag.PixelSpacing))
+	base.setOrientation(TagFloats(data, tag.ImageOrientationPatient))
+	base.setPosition(TagFloats(data, tag.ImagePositionPatient))
+
+	shared := sequenceItems(findElement(data.Elements, tag.SharedFunctionalGroupsSequence))
+	if len(shared) > 0 {
+		base.readFunctionalGroup(shared[0])
+	}
+
+	perFrame := sequenceItems(findElement(data.Elements, tag.PerFrameFunctionalGroupsSequence))
+	planes := make([]ImagePlane, frames)
+	for i := range planes {
+		planes[i] = base
+		if i < len(perFrame) {
+			planes[i].readFunctionalGroup(perFrame[i])
+		}
+		planes[i].valid = planes[i].valid && planes[i].Orientation != [6]float64{}
+	}
+
+	return planes
+}
+
+func (p *ImagePlane) readFunctionalGroup(elems []*dicom.Element) {
+	p.setSpacing(elementFloats(nestedElement(elems, tag.PixelMeasuresSequence, tag.PixelSpacing)))
+	p.setOrientation(elementFloats(nestedElement(elems, tag.PlaneOrientationSequence, tag.ImageOrientationPatient)))
+	p.setPosition(elementFloats(nestedElement(elems, tag.PlanePositionSequence, tag.ImagePositionPatient)))
+}
+
+func (p *ImagePlane) setOrientation(vals []float64) {
+	if len(vals) == 6 {
+		copy(p.Orientation[:], vals)
+	}
+}
+
+func (p *ImagePlane) setPosition(vals []float64) {
+	if len(vals) == 3 {
+		copy(p.Position[:], vals)
+		p.valid = true
+	}
+}
+
+func (p *ImagePlane) setSpacing(vals []float64) {
+	if len(vals) == 2 {
+		copy(p.Spacing[:], vals)
+	}
+}
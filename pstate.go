@@ -0,0 +1,911 @@
+package dicomgraphics
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+	"github.com/suyashkumar/dicom/pkg/uid"
+)
+
+const (
+	// GrayscaleSoftcopyPresentationStateStorage is the SOP Class UID of a GSPS object.
+	GrayscaleSoftcopyPresentationStateStorage = "1.2.840.10008.5.1.4.1.1.11.1"
+	// ColorSoftcopyPresentationStateStorage is the SOP Class UID of a CSPS object.
+	ColorSoftcopyPresentationStateStorage = "1.2.840.10008.5.1.4.1.1.11.2"
+
+	// SizeScaleToFit scales the displayed area to fill the available space.
+	SizeScaleToFit = "SCALE TO FIT"
+	// SizeTrueSize displays the area at its physical size.
+	SizeTrueSize = "TRUE SIZE"
+	// SizeMagnify scales the displayed area by its magnification ratio.
+	SizeMagnify = "MAGNIFY"
+
+	annotationLayer     = "ANNOTATIONS"
+	ellipseSegments     = 64
+	maxCodeStringLength = 16
+)
+
+// ErrNotPresentationState is returned when parsing a dataset that is not a softcopy presentation state.
+var ErrNotPresentationState = errors.New("dataset is not a softcopy presentation state")
+
+// ErrColorPresentationState is returned when parsing a Color Softcopy Presentation State,
+// whose ICC profile and colour annotations cannot be applied.
+var ErrColorPresentationState = errors.New("colour softcopy presentation states are not supported")
+
+var (
+	graphicGroupLabels = map[MeasurementKind]string{
+		MeasureRuler:     "RULER",
+		MeasureAngle:     "ANGLE",
+		MeasureCobb:      "COBB",
+		MeasureRectangle: "RECTANGLE",
+		MeasureEllipse:   "ELLIPSE",
+		MeasureFreehand:  "FREEHAND",
+	}
+
+	// patientStudyTags are copied from the referenced images so the presentation state files with its study.
+	patientStudyTags = []tag.Tag{
+		tag.SpecificCharacterSet, tag.PatientName, tag.PatientID, tag.PatientBirthDate, tag.PatientSex,
+		tag.StudyInstanceUID, tag.StudyDate, tag.StudyTime, tag.ReferringPhysicianName, tag.StudyID,
+		tag.AccessionNumber, tag.StudyDescription,
+	}
+)
+
+// ImageReference identifies an image, or a single frame of an image, that a presentation state applies to.
+type ImageReference struct {
+	SOPClassUID, SOPInstanceUID, SeriesInstanceUID string
+	// Frame is the frame number, starting at 1, or 0 to reference every frame.
+	Frame int
+}
+
+// NewImageReference returns the reference to the image or frame of a slice.
+func NewImageReference(slice *Slice) ImageReference {
+	ref := ImageReference{
+		SOPClassUID:       TagString(slice.Data, tag.SOPClassUID),
+		SOPInstanceUID:    TagString(slice.Data, tag.SOPInstanceUID),
+		SeriesInstanceUID: TagString(slice.Data, tag.SeriesInstanceUID),
+	}
+	if frames := TagFloats(slice.Data, tag.NumberOfFrames); len(frames) > 0 && frames[0] > 1 {
+		ref.Frame = slice.Index + 1
+	}
+	return ref
+}
+
+// Matches returns true if this reference applies to the other image or frame.
+func (r ImageReference) Matches(other ImageReference) bool {
+	return r.SOPInstanceUID == other.SOPInstanceUID && (r.Frame == 0 || other.Frame == 0 || r.Frame == other.Frame)
+}
+
+// appliesTo returns true if any of the references match, or if there are none as the item applies to every image.
+func appliesTo(refs []ImageReference, ref ImageReference) bool {
+	if len(refs) == 0 {
+		return true
+	}
+
+	for _, r := range refs {
+		if r.Matches(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// TextAnnotation is text anchored to a point on an image.
+type TextAnnotation struct {
+	Text   string
+	Anchor Point
+	// Display is set when the anchor is a fraction of the displayed area rather than an image pixel.
+	Display bool
+}
+
+// Graphic is a shape drawn by a presentation state that was not saved from one of the measurement tools.
+type Graphic struct {
+	// Type is one of POINT, POLYLINE, INTERPOLATED, CIRCLE or ELLIPSE.
+	Type   string
+	Points []Point
+	Filled bool
+	// Display is set when the points are fractions of the displayed area rather than image pixels.
+	Display bool
+}
+
+// Outline returns the points of a line tracing the graphic.
+// Circles are given by their centre and a point on the edge, and ellipses by the ends of their major then minor axes.
+func (g Graphic) Outline() []Point {
+	p := g.Points
+	switch g.Type {
+	case "CIRCLE":
+		if len(p) < 2 {
+			return nil
+		}
+		r := math.Hypot(p[1].X-p[0].X, p[1].Y-p[0].Y)
+		return ellipseOutline(p[0], Point{X: r}, Point{Y: r})
+	case "ELLIPSE":
+		if len(p) < 4 {
+			return nil
+		}
+		centre := Point{X: (p[0].X + p[1].X) / 2, Y: (p[0].Y + p[1].Y) / 2}
+		major := Point{X: (p[1].X - p[0].X) / 2, Y: (p[1].Y - p[0].Y) / 2}
+		length := math.Hypot(major.X, major.Y)
+		if length == 0 {
+			return nil
+		}
+		minor := math.Hypot(p[3].X-p[2].X, p[3].Y-p[2].Y) / 2
+		return ellipseOutline(centre, major, Point{X: -major.Y / length * minor, Y: major.X / length * minor})
+	default:
+		return p
+	}
+}
+
+func ellipseOutline(centre, axis1, axis2 Point) []Point {
+	points := make([]Point, ellipseSegments+1)
+	for i := range points {
+		a := 2 * math.Pi * float64(i) / ellipseSegments
+		cos, sin := math.Cos(a), math.Sin(a)
+		points[i] = Point{X: centre.X + axis1.X*cos + axis2.X*sin, Y: centre.Y + axis1.Y*cos + axis2.Y*sin}
+	}
+	return points
+}
+
+// Annotation holds the graphics and text drawn on a single image.
+type Annotation struct {
+	Image        ImageReference
+	Measurements []*Measurement
+	Graphics     []Graphic
+	Texts        []TextAnnotation
+	// Calibration is used to label the measurements when they are saved.
+	Calibration Calibration
+}
+
+// Window is the VOI window that a presentation state applies to some of its images.
+type Window struct {
+	// Images lists the images using this window, or is empty if it applies to every image.
+	Images       []ImageReference
+	Level, Width float64
+}
+
+// DisplayedArea is the part of an image that a presentation state shows, and how it is sized.
+type DisplayedArea struct {
+	// Images lists the images showing this area, or is empty if it applies to every image.
+	Images               []ImageReference
+	TopLeft, BottomRight Point
+	// SizeMode is SizeScaleToFit, SizeTrueSize or SizeMagnify.
+	SizeMode      string
+	Magnification float64
+	// AspectRatio is the height of each displayed pixel divided by its width, or 0 for square pixels.
+	AspectRatio float64
+}
+
+// PresentationState describes how a set of images should be displayed,
+// as stored in a DICOM Grayscale Softcopy Presentation State.
+type PresentationState struct {
+	Label, Description, Creator string
+
+	Images []ImageReference
+
+	// HasRescale is set when the slope and intercept replace the rescale of the images.
+	HasRescale                     bool
+	RescaleSlope, RescaleIntercept float64
+
+	Windows        []Window
+	DisplayedAreas []DisplayedArea
+
+	// Rotation is the clockwise rotation in degrees, one of 0, 90, 180 or 270, applied after Flip as in Transform.
+	// The Image Rotation of the file is applied before the flip, so it is reversed when read and written.
+	Rotation int
+	// Flip mirrors the image horizontally.
+	Flip bool
+	// Inverse displays the minimum value as white, from a Presentation LUT Shape of INVERSE.
+	Inverse bool
+
+	// Shutter hides parts of the images, or is nil if they are shown in full.
+	// A bitmap shutter is read but not written, as it is stored in an overlay plane.
+	Shutter *Shutter
+
+	Annotations []*Annotation
+}
+
+// AnnotationsFor returns the annotations that apply to an image or frame.
+// This includes annotations that do not reference a particular image, which apply to every referenced image.
+func (p *PresentationState) AnnotationsFor(ref ImageReference) []*Annotation {
+	var list []*Annotation
+	for _, a := range p.Annotations {
+		if a.Image.SOPInstanceUID == "" || a.Image.Matches(ref) {
+			list = append(list, a)
+		}
+	}
+
+	return list
+}
+
+// WindowFor returns the window for an image or frame. The bool is false if the image window should be kept.
+func (p *PresentationState) WindowFor(ref ImageReference) (Window, bool) {
+	for _, w := range p.Windows {
+		if appliesTo(w.Images, ref) {
+			return w, true
+		}
+	}
+
+	return Window{}, false
+}
+
+// DisplayedAreaFor returns the area to show of an image or frame. The bool is false if the whole image is shown.
+func (p *PresentationState) DisplayedAreaFor(ref ImageReference) (DisplayedArea, bool) {
+	for _, a := range p.DisplayedAreas {
+		if appliesTo(a.Images, ref) {
+			return a, true
+		}
+	}
+
+	return DisplayedArea{}, false
+}
+
+// References returns true if the presentation state applies to the given image or frame.
+func (p *PresentationState) References(ref ImageReference) bool {
+	for _, r := range p.Images {
+		if r.Matches(ref) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Write encodes the presentation state as a DICOM file.
+// The patient and study details are copied from source, which should be one of the referenced images.
+func (p *PresentationState) Write(w io.Writer, source *dicom.Dataset) error {
+	data, err := p.Dataset(source)
+	if err != nil {
+		return err
+	}
+
+	return dicom.Write(w, data)
+}
+
+// Dataset encodes the presentation state as a DICOM GSPS dataset.
+// The patient and study details are copied from source, which should be one of the referenced images.
+func (p *PresentationState) Dataset(source *dicom.Dataset) (dicom.Dataset, error) {
+	instance := NewUID()
+	now := time.Now()
+
+	e := &elementList{}
+	e.add(tag.FileMetaInformationVersion, []byte{0, 1})
+	e.add(tag.MediaStorageSOPClassUID, []string{GrayscaleSoftcopyPresentationStateStorage})
+	e.add(tag.MediaStorageSOPInstanceUID, []string{instance})
+	e.add(tag.TransferSyntaxUID, []string{uid.ExplicitVRLittleEndian})
+
+	for _, t := range patientStudyTags {
+		if elem := findElement(source.Elements, t); elem != nil {
+			e.elems = append(e.elems, elem)
+		}
+	}
+	e.add(tag.SOPClassUID, []string{GrayscaleSoftcopyPresentationStateStorage})
+	e.add(tag.SOPInstanceUID, []string{instance})
+	e.add(tag.Modality, []string{"PR"})
+	e.add(tag.SeriesInstanceUID, []string{NewUID()})
+	e.add(tag.SeriesNumber, []string{"1"})
+	e.add(tag.InstanceNumber, []string{"1"})
+	e.add(tag.Manufacturer, []string{"dicomgraphics"})
+	e.add(tag.ContentLabel, []string{contentLabel(p.Label)})
+	e.add(tag.ContentDescription, []string{p.Description})
+	e.add(tag.ContentCreatorName, []string{p.Creator})
+	e.add(tag.PresentationCreationDate, []string{now.Format("20060102")})
+	e.add(tag.PresentationCreationTime, []string{now.Format("150405")})
+
+	e.add(tag.ReferencedSeriesSequence, p.referencedSeries())
+	if p.HasRescale {
+		e.add(tag.RescaleIntercept, []string{formatDecimal(p.RescaleIntercept)})
+		e.add(tag.RescaleSlope, []string{formatDecimal(p.RescaleSlope)})
+		e.add(tag.RescaleType, []string{"US"})
+	}
+
+	areas, err := p.displayedAreas(source)
+	e.err = firstError(e.err, err)
+	e.add(tag.DisplayedAreaSelectionSequence, areas)
+	if len(p.Windows) > 0 {
+		var windows [][]*dicom.Element
+		for _, w := range p.Windows {
+			voi := &elementList{}
+			addImageReferences(voi, w.Images)
+			voi.add(tag.WindowCenter, []string{formatDecimal(w.Level)})
+			voi.add(tag.WindowWidth, []string{formatDecimal(w.Width)})
+			e.err = firstError(e.err, voi.err)
+			windows = append(windows, voi.elems)
+		}
+		e.add(tag.SoftcopyVOILUTSequence, windows)
+	}
+	if p.Rotation != 0 || p.Flip {
+		rotation, flip := normaliseRotation(p.Rotation), "N"
+		if p.Flip {
+			rotation, flip = normaliseRotation(-rotation), "Y"
+		}
+		e.add(tag.ImageRotation, []int{rotation})
+		e.add(tag.ImageHorizontalFlip, []string{flip})
+	}
+	shape := "IDENTITY"
+	if p.Inverse {
+		shape = "INVERSE"
+	}
+	e.add(tag.PresentationLUTShape, []string{shape})
+	if p.Shutter != nil {
+		addShutter(e, p.Shutter)
+	}
+
+	if len(p.Annotations) > 0 {
+		layer := &elementList{}
+		layer.add(tag.GraphicLayer, []string{annotationLayer})
+		layer.add(tag.GraphicLayerOrder, []string{"1"})
+		layer.add(tag.GraphicLayerRecommendedDisplayGrayscaleValue, []int{0xffff})
+		e.add(tag.GraphicLayerSequence, [][]*dicom.Element{layer.elems})
+		e.err = firstError(e.err, layer.err)
+
+		annotations, groups, err := p.graphicAnnotations()
+		e.err = firstError(e.err, err)
+		e.add(tag.GraphicAnnotationSequence, annotations)
+		if len(groups) > 0 {
+			e.add(tag.GraphicGroupSequence, groups)
+		}
+	}
+
+	if e.err != nil {
+		return dicom.Dataset{}, e.err
+	}
+	sortElements(e.elems)
+	return dicom.Dataset{Elements: e.elems}, nil
+}
+
+func (p *PresentationState) displayedAreas(source *dicom.Dataset) ([][]*dicom.Element, error) {
+	areas := p.DisplayedAreas
+	if len(areas) == 0 {
+		full := DisplayedArea{}
+		cols, rows := TagFloats(source, tag.Columns), TagFloats(source, tag.Rows)
+		if len(cols) > 0 && len(rows) > 0 {
+			full.BottomRight = Point{X: cols[0], Y: rows[0]}
+		}
+		areas = []DisplayedArea{full}
+	}
+
+	var items [][]*dicom.Element
+	for _, a := range areas {
+		mode := a.SizeMode
+		if mode == "" || mode == SizeTrueSize {
+			mode = SizeScaleToFit // true size also needs the physical pixel spacing
+		}
+		aspect := []string{"1", "1"}
+		if a.AspectRatio > 0 && a.AspectRatio != 1 {
+			aspect = []string{strconv.Itoa(int(math.Round(a.AspectRatio * 1000))), "1000"}
+		}
+
+		area := &elementList{}
+		addImageReferences(area, a.Images)
+		area.add(tag.DisplayedAreaTopLeftHandCorner, []int{int(math.Floor(a.TopLeft.X)) + 1, int(math.Floor(a.TopLeft.Y)) + 1})
+		area.add(tag.DisplayedAreaBottomRightHandCorner, []int{int(math.Ceil(a.BottomRight.X)), int(math.Ceil(a.BottomRight.Y))})
+		area.add(tag.PresentationSizeMode, []string{mode})
+		area.add(tag.PresentationPixelAspectRatio, aspect)
+		if mode == SizeMagnify {
+			area.add(tag.PresentationPixelMagnificationRatio, []float64{a.Magnification})
+		}
+		if area.err != nil {
+			return nil, area.err
+		}
+		items = append(items, area.elems)
+	}
+	return items, nil
+}
+
+func (p *PresentationState) referencedSeries() [][]*dicom.Element {
+	var order []string
+	images := make(map[string][][]*dicom.Element)
+	for _, ref := range p.Images {
+		if _, ok := images[ref.SeriesInstanceUID]; !ok {
+			order = append(order, ref.SeriesInstanceUID)
+		}
+		images[ref.SeriesInstanceUID] = append(images[ref.SeriesInstanceUID], imageReferenceElements(ref))
+	}
+
+	var series [][]*dicom.Element
+	for _, id := range order {
+		item := &elementList{}
+		item.add(tag.SeriesInstanceUID, []string{id})
+		item.add(tag.ReferencedImageSequence, images[id])
+		series = append(series, item.elems)
+	}
+	return series
+}
+
+func (p *PresentationState) graphicAnnotations() ([][]*dicom.Element, [][]*dicom.Element, error) {
+	var items, groups [][]*dicom.Element
+	groupID := 0
+	for _, a := range p.Annotations {
+		var graphics, texts [][]*dicom.Element
+		for _, m := range a.Measurements {
+			if !m.Complete() {
+				continue
+			}
+
+			groupID++
+			group := &elementList{}
+			group.add(tag.GraphicGroupID, []int{groupID})
+			group.add(tag.GraphicGroupLabel, []string{graphicGroupLabels[m.Kind]})
+			groups = append(groups, group.elems)
+
+			for _, g := range measurementGraphics(m) {
+				graphic := graphicElements(g)
+				graphic.add(tag.GraphicGroupID, []int{groupID})
+				if graphic.err != nil {
+					return nil, nil, graphic.err
+				}
+				graphics = append(graphics, graphic.elems)
+			}
+
+			label := TextAnnotation{Text: m.Label(a.Calibration), Anchor: m.Points[len(m.Points)-1]}
+			text := textElements(label)
+			text.add(tag.GraphicGroupID, []int{groupID})
+			if text.err != nil {
+				return nil, nil, text.err
+			}
+			texts = append(texts, text.elems)
+		}
+		for _, g := range a.Graphics {
+			graphic := graphicElements(g)
+			if graphic.err != nil {
+				return nil, nil, graphic.err
+			}
+			graphics = append(graphics, graphic.elems)
+		}
+		for _, t := range a.Texts {
+			text := textElements(t)
+			if text.err != nil {
+				return nil, nil, text.err
+			}
+			texts = append(texts, text.elems)
+		}
+
+		item := &elementList{}
+		if a.Image.SOPInstanceUID != "" {
+			item.add(tag.ReferencedImageSequence, [][]*dicom.Element{imageReferenceElements(a.Image)})
+		}
+		item.add(tag.GraphicLayer, []string{annotationLayer})
+		if len(texts) > 0 {
+			item.add(tag.TextObjectSequence, texts)
+		}
+		if len(graphics) > 0 {
+			item.add(tag.GraphicObjectSequence, graphics)
+		}
+		if item.err != nil {
+			return nil, nil, item.err
+		}
+		items = append(items, item.elems)
+	}
+
+	return items, groups, nil
+}
+
+// LoadPresentationState reads a presentation state file.
+func LoadPresentationState(path string) (*PresentationState, error) {
+	data, err := dicom.ParseFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParsePresentationState(&data)
+}
+
+// ParsePresentationState reads the display settings and annotations from a presentation state dataset.
+func ParsePresentationState(data *dicom.Dataset) (*PresentationState, error) {
+	switch TagString(data, tag.SOPClassUID) {
+	case GrayscaleSoftcopyPresentationStateStorage:
+	case ColorSoftcopyPresentationStateStorage:
+		return nil, ErrColorPresentationState
+	default:
+		return nil, ErrNotPresentationState
+	}
+
+	p := &PresentationState{
+		Label:       TagString(data, tag.ContentLabel),
+		Description: TagString(data, tag.ContentDescription),
+		Creator:     TagString(data, tag.ContentCreatorName),
+		Inverse:     strings.TrimSpace(TagString(data, tag.PresentationLUTShape)) == "INVERSE",
+		Flip:        strings.TrimSpace(TagString(data, tag.ImageHorizontalFlip)) == "Y",
+		Shutter:     ParseShutter(data),
+	}
+	if rotation := TagFloats(data, tag.ImageRotation); len(rotation) > 0 {
+		p.Rotation = normaliseRotation(int(rotation[0]))
+		if p.Flip {
+			p.Rotation = normaliseRotation(-p.Rotation)
+		}
+	}
+	slope, intercept := TagFloats(data, tag.RescaleSlope), TagFloats(data, tag.RescaleIntercept)
+	if len(slope) > 0 && len(intercept) > 0 {
+		p.HasRescale, p.RescaleSlope, p.RescaleIntercept = true, slope[0], intercept[0]
+	}
+
+	for _, series := range sequenceItems(findElement(data.Elements, tag.ReferencedSeriesSequence)) {
+		seriesUID := strings.Join(elementStrings(findElement(series, tag.SeriesInstanceUID)), "")
+		for _, image := range sequenceItems(findElement(series, tag.ReferencedImageSequence)) {
+			for _, ref := range parseImageReferences(image) {
+				ref.SeriesInstanceUID = seriesUID
+				p.Images = append(p.Images, ref)
+			}
+		}
+	}
+
+	for _, voi := range sequenceItems(findElement(data.Elements, tag.SoftcopyVOILUTSequence)) {
+		center := elementFloats(findElement(voi, tag.WindowCenter))
+		width := elementFloats(findElement(voi, tag.WindowWidth))
+		if len(center) > 0 && len(width) > 0 {
+			p.Windows = append(p.Windows, Window{Images: referencedImages(voi), Level: center[0], Width: width[0]})
+		}
+	}
+
+	for _, item := range sequenceItems(findElement(data.Elements, tag.DisplayedAreaSelectionSequence)) {
+		if area, ok := parseDisplayedArea(item); ok {
+			p.DisplayedAreas = append(p.DisplayedAreas, area)
+		}
+	}
+
+	groups := make(map[int]MeasurementKind)
+	for _, group := range sequenceItems(findElement(data.Elements, tag.GraphicGroupSequence)) {
+		id := elementFloats(findElement(group, tag.GraphicGroupID))
+		label := strings.TrimSpace(strings.Join(elementStrings(findElement(group, tag.GraphicGroupLabel)), ""))
+		for kind, name := range graphicGroupLabels {
+			if name == label && len(id) > 0 {
+				groups[int(id[0])] = kind
+			}
+		}
+	}
+	for _, item := range sequenceItems(findElement(data.Elements, tag.GraphicAnnotationSequence)) {
+		p.parseAnnotation(item, groups)
+	}
+
+	return p, nil
+}
+
+func parseDisplayedArea(item []*dicom.Element) (DisplayedArea, bool) {
+	topLeft := elementFloats(findElement(item, tag.DisplayedAreaTopLeftHandCorner))
+	bottomRight := elementFloats(findElement(item, tag.DisplayedAreaBottomRightHandCorner))
+	if len(topLeft) != 2 || len(bottomRight) != 2 {
+		return DisplayedArea{}, false
+	}
+
+	area := DisplayedArea{
+		Images:      referencedImages(item),
+		TopLeft:     Point{X: topLeft[0] - 1, Y: topLeft[1] - 1},
+		BottomRight: Point{X: bottomRight[0], Y: bottomRight[1]},
+		SizeMode:    strings.TrimSpace(strings.Join(elementStrings(findElement(item, tag.PresentationSizeMode)), "")),
+	}
+	if ratio := elementFloats(findElement(item, tag.PresentationPixelMagnificationRatio)); len(ratio) > 0 {
+		area.Magnification = ratio[0]
+	}
+	if aspect := elementFloats(findElement(item, tag.PresentationPixelAspectRatio)); len(aspect) == 2 && aspect[1] > 0 {
+		area.AspectRatio = aspect[0] / aspect[1]
+	}
+	return area, true
+}
+
+func (p *PresentationState) parseAnnotation(item []*dicom.Element, groups map[int]MeasurementKind) {
+	refs := referencedImages(item)
+	if len(refs) == 0 {
+		// Annotations without references apply to every image in the presentation state.
+		refs = []ImageReference{{}}
+	}
+
+	for _, ref := range refs {
+		a := &Annotation{Image: ref}
+		grouped := make(map[int]*Measurement)
+		for _, graphic := range sequenceItems(findElement(item, tag.GraphicObjectSequence)) {
+			g := Graphic{
+				Type:    elementString(findElement(graphic, tag.GraphicType)),
+				Points:  dataPoints(elementFloats(findElement(graphic, tag.GraphicData))),
+				Filled:  elementString(findElement(graphic, tag.GraphicFilled)) == "Y",
+				Display: elementString(findElement(graphic, tag.GraphicAnnotationUnits)) == "DISPLAY",
+			}
+
+			id := elementFloats(findElement(graphic, tag.GraphicGroupID))
+			if kind, ok := measurementGroup(groups, id); ok && !g.Display {
+				if m, ok := grouped[int(id[0])]; ok {
+					m.Points = append(m.Points, g.Points...)
+				} else {
+					m = &Measurement{Kind: kind, Points: g.Points}
+					grouped[int(id[0])] = m
+					a.Measurements = append(a.Measurements, m)
+				}
+				continue
+			}
+
+			a.Graphics = append(a.Graphics, g)
+		}
+		for _, m := range a.Measurements {
+			normaliseMeasurement(m)
+		}
+
+		for _, text := range sequenceItems(findElement(item, tag.TextObjectSequence)) {
+			if _, ok := measurementGroup(groups, elementFloats(findElement(text, tag.GraphicGroupID))); ok {
+				continue // measurement labels are recalculated
+			}
+
+			t := TextAnnotation{Text: strings.Join(elementStrings(findElement(text, tag.UnformattedTextValue)), "\\")}
+			anchor := elementFloats(findElement(text, tag.AnchorPoint))
+			t.Display = elementString(findElement(text, tag.AnchorPointAnnotationUnits)) == "DISPLAY"
+			if len(anchor) != 2 {
+				anchor = elementFloats(findElement(text, tag.BoundingBoxTopLeftHandCorner))
+				t.Display = elementString(findElement(text, tag.BoundingBoxAnnotationUnits)) == "DISPLAY"
+			}
+			if len(anchor) == 2 {
+				t.Anchor = Point{X: anchor[0], Y: anchor[1]}
+				a.Texts = append(a.Texts, t)
+			}
+		}
+
+		p.Annotations = append(p.Annotations, a)
+	}
+}
+
+func measurementGroup(groups map[int]MeasurementKind, id []float64) (MeasurementKind, bool) {
+	if len(id) == 0 {
+		return 0, false
+	}
+
+	kind, ok := groups[int(id[0])]
+	return kind, ok
+}
+
+// measurementGraphics returns the DICOM graphic objects that draw a measurement.
+func measurementGraphics(m *Measurement) []Graphic {
+	p := m.Points
+	switch m.Kind {
+	case MeasureCobb:
+		return []Graphic{{Type: "POLYLINE", Points: p[:2]}, {Type: "POLYLINE", Points: p[2:4]}}
+	case MeasureRectangle:
+		return []Graphic{{Type: "POLYLINE", Points: []Point{p[0], {X: p[1].X, Y: p[0].Y}, p[1], {X: p[0].X, Y: p[1].Y}, p[0]}}}
+	case MeasureEllipse:
+		cx, cy := (p[0].X+p[1].X)/2, (p[0].Y+p[1].Y)/2
+		rx, ry := math.Abs(p[1].X-p[0].X)/2, math.Abs(p[1].Y-p[0].Y)/2
+		horizontal := []Point{{X: cx - rx, Y: cy}, {X: cx + rx, Y: cy}}
+		vertical := []Point{{X: cx, Y: cy - ry}, {X: cx, Y: cy + ry}}
+		if rx >= ry {
+			return []Graphic{{Type: "ELLIPSE", Points: append(horizontal, vertical...)}}
+		}
+		return []Graphic{{Type: "ELLIPSE", Points: append(vertical, horizontal...)}}
+	case MeasureFreehand:
+		return []Graphic{{Type: "POLYLINE", Points: append(append([]Point{}, p...), p[0])}}
+	default:
+		return []Graphic{{Type: "POLYLINE", Points: p}}
+	}
+}
+
+// normaliseMeasurement converts the points of saved graphics back to those used when drawing.
+func normaliseMeasurement(m *Measurement) {
+	switch m.Kind {
+	case MeasureRectangle, MeasureEllipse:
+		if len(m.Points) == 2 {
+			return
+		}
+		minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+		for _, p := range m.Points {
+			minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+			minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+		}
+		m.Points = []Point{{X: minX, Y: minY}, {X: maxX, Y: maxY}}
+	case MeasureFreehand:
+		if n := len(m.Points); n > 1 && m.Points[0] == m.Points[n-1] {
+			m.Points = m.Points[:n-1]
+		}
+	}
+}
+
+func imageReferenceElements(ref ImageReference) []*dicom.Element {
+	e := &elementList{}
+	e.add(tag.ReferencedSOPClassUID, []string{ref.SOPClassUID})
+	e.add(tag.ReferencedSOPInstanceUID, []string{ref.SOPInstanceUID})
+	if ref.Frame > 0 {
+		e.add(tag.ReferencedFrameNumber, []string{strconv.Itoa(ref.Frame)})
+	}
+	return e.elems
+}
+
+func addImageReferences(e *elementList, refs []ImageReference) {
+	if len(refs) == 0 {
+		return
+	}
+
+	var items [][]*dicom.Element
+	for _, ref := range refs {
+		items = append(items, imageReferenceElements(ref))
+	}
+	e.add(tag.ReferencedImageSequence, items)
+}
+
+// referencedImages returns the images in the Referenced Image Sequence of an item.
+func referencedImages(elems []*dicom.Element) []ImageReference {
+	var refs []ImageReference
+	for _, item := range sequenceItems(findElement(elems, tag.ReferencedImageSequence)) {
+		refs = append(refs, parseImageReferences(item)...)
+	}
+	return refs
+}
+
+// parseImageReferences returns a reference for each frame listed in an image reference, or for the whole image.
+func parseImageReferences(elems []*dicom.Element) []ImageReference {
+	ref := ImageReference{
+		SOPClassUID:    elementString(findElement(elems, tag.ReferencedSOPClassUID)),
+		SOPInstanceUID: elementString(findElement(elems, tag.ReferencedSOPInstanceUID)),
+	}
+	frames := elementFloats(findElement(elems, tag.ReferencedFrameNumber))
+	if len(frames) == 0 {
+		return []ImageReference{ref}
+	}
+
+	refs := make([]ImageReference, len(frames))
+	for i, f := range frames {
+		refs[i] = ref
+		refs[i].Frame = int(f)
+	}
+	return refs
+}
+
+func addShutter(e *elementList, s *Shutter) {
+	var shapes []string
+	if s.Rectangular {
+		shapes = append(shapes, "RECTANGULAR")
+		e.add(tag.ShutterLeftVerticalEdge, []string{strconv.Itoa(s.Left)})
+		e.add(tag.ShutterRightVerticalEdge, []string{strconv.Itoa(s.Right)})
+		e.add(tag.ShutterUpperHorizontalEdge, []string{strconv.Itoa(s.Upper)})
+		e.add(tag.ShutterLowerHorizontalEdge, []string{strconv.Itoa(s.Lower)})
+	}
+	if s.Circular {
+		shapes = append(shapes, "CIRCULAR")
+		e.add(tag.CenterOfCircularShutter, []string{
+			strconv.Itoa(int(math.Round(s.Centre.Y))), strconv.Itoa(int(math.Round(s.Centre.X)))})
+		e.add(tag.RadiusOfCircularShutter, []string{strconv.Itoa(int(math.Round(s.Radius)))})
+	}
+	if len(s.Vertices) >= 3 {
+		shapes = append(shapes, "POLYGONAL")
+		var vertices []string
+		for _, v := range s.Vertices {
+			vertices = append(vertices, strconv.Itoa(int(math.Round(v.Y))), strconv.Itoa(int(math.Round(v.X))))
+		}
+		e.add(tag.VerticesOfThePolygonalShutter, vertices)
+	}
+	// a shutter that is only a bitmap has no shape to write
+	if len(shapes) == 0 {
+		return
+	}
+	e.add(tag.ShutterShape, shapes)
+	e.add(tag.ShutterPresentationValue, []int{int(s.Value)})
+}
+
+func graphicElements(g Graphic) *elementList {
+	units := "PIXEL"
+	if g.Display {
+		units = "DISPLAY"
+	}
+	filled := "N"
+	if g.Filled {
+		filled = "Y"
+	}
+
+	e := &elementList{}
+	e.add(tag.GraphicAnnotationUnits, []string{units})
+	e.add(tag.GraphicDimensions, []int{2})
+	e.add(tag.NumberOfGraphicPoints, []int{len(g.Points)})
+	e.add(tag.GraphicData, pointData(g.Points))
+	e.add(tag.GraphicType, []string{g.Type})
+	e.add(tag.GraphicFilled, []string{filled})
+	return e
+}
+
+func textElements(t TextAnnotation) *elementList {
+	units := "PIXEL"
+	if t.Display {
+		units = "DISPLAY"
+	}
+
+	e := &elementList{}
+	e.add(tag.AnchorPointAnnotationUnits, []string{units})
+	e.add(tag.UnformattedTextValue, []string{t.Text})
+	e.add(tag.AnchorPoint, []float64{t.Anchor.X, t.Anchor.Y})
+	e.add(tag.AnchorPointVisibility, []string{"N"})
+	return e
+}
+
+func pointData(points []Point) []float64 {
+	data := make([]float64, 0, len(points)*2)
+	for _, p := range points {
+		data = append(data, p.X, p.Y)
+	}
+	return data
+}
+
+func dataPoints(data []float64) []Point {
+	points := make([]Point, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		points = append(points, Point{X: data[i], Y: data[i+1]})
+	}
+	return points
+}
+
+// elementList builds a list of elements, remembering the first error encountered.
+type elementList struct {
+	elems []*dicom.Element
+	err   error
+}
+
+func (l *elementList) add(t tag.Tag, data interface{}) {
+	if l.err != nil {
+		return
+	}
+
+	elem, err := dicom.NewElement(t, data)
+	if err != nil {
+		l.err = fmt.Errorf("creating element %s: %w", t, err)
+		return
+	}
+	if elem.RawValueRepresentation == "SQ" {
+		elem.ValueLength = tag.VLUndefinedLength
+	}
+	l.elems = append(l.elems, elem)
+}
+
+// sortElements puts elements, and those within their sequences, into the ascending tag order required by DICOM.
+func sortElements(elems []*dicom.Element) {
+	sort.SliceStable(elems, func(i, j int) bool {
+		a, b := elems[i].Tag, elems[j].Tag
+		return a.Group < b.Group || (a.Group == b.Group && a.Element < b.Element)
+	})
+
+	for _, elem := range elems {
+		for _, item := range sequenceItems(elem) {
+			sortElements(item)
+		}
+	}
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contentLabel returns a label as a Code String, upper case and at most 16 characters long.
+func contentLabel(label string) string {
+	if label == "" {
+		return "ANNOTATIONS"
+	}
+
+	label = strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(label))
+	if len(label) > maxCodeStringLength {
+		label = label[:maxCodeStringLength]
+	}
+	return label
+}
+
+func formatDecimal(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// NewUID returns a new globally unique DICOM UID, derived from a random UUID as described in PS3.5 B.2.
+func NewUID() string {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		n = big.NewInt(time.Now().UnixNano())
+	}
+
+	return "2.25." + n.String()
+}
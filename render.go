@@ -0,0 +1,192 @@
+package dicomgraphics
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+const (
+	annotationLineWidth = 1.5
+	labelOffset         = 8
+)
+
+// asciiLabels replaces the characters of measurement labels that are missing from the built in font.
+var asciiLabels = strings.NewReplacer("²", "2", "°", " deg")
+
+// AnnotationColor is used to draw the graphics and text of a presentation state.
+var AnnotationColor color.Color = color.White
+
+// Apply sets the rescale, window, presentation LUT shape and shutter of an image from the presentation state.
+// The settings for the image or frame identified by ref are used.
+func (p *PresentationState) Apply(img *DICOMImage, ref ImageReference) {
+	if p.HasRescale {
+		img.SetRescale(p.RescaleSlope, p.RescaleIntercept)
+	}
+	// windows outside of the 16 bit range are clamped to it
+	if w, ok := p.WindowFor(ref); ok && ClampInt16(w.Width) > 0 {
+		img.SetWindowLevel(ClampInt16(w.Level))
+		img.SetWindowWidth(ClampInt16(w.Width))
+	}
+	img.SetInverse(p.Inverse)
+	// the shutter of a presentation state replaces that of the image
+	img.SetShutter(p.Shutter)
+}
+
+// Render draws an image as the presentation state displays it.
+// The settings are applied to img, then its displayed area is drawn at the requested magnification and aspect ratio,
+// with the annotations for ref over it, before being flipped and rotated.
+// Measurement labels use the given calibration.
+func (p *PresentationState) Render(img *DICOMImage, ref ImageReference, cal Calibration) *image.RGBA {
+	p.Apply(img, ref)
+
+	b := img.Bounds()
+	area, ok := p.DisplayedAreaFor(ref)
+	if !ok {
+		area = DisplayedArea{BottomRight: Point{X: float64(b.Dx()), Y: float64(b.Dy())}}
+	}
+	scaleX, scaleY := 1.0, 1.0
+	if area.SizeMode == SizeMagnify && area.Magnification > 0 {
+		scaleX, scaleY = area.Magnification, area.Magnification
+	}
+	if area.AspectRatio > 0 {
+		scaleY *= area.AspectRatio
+	}
+
+	w := int(math.Round((area.BottomRight.X - area.TopLeft.X) * scaleX))
+	h := int(math.Round((area.BottomRight.Y - area.TopLeft.Y) * scaleY))
+	if w < 1 || h < 1 {
+		return image.NewRGBA(image.Rectangle{})
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.Black, image.Point{}, draw.Src)
+	s2d := f64.Aff3{
+		scaleX, 0, -area.TopLeft.X * scaleX,
+		0, scaleY, -area.TopLeft.Y * scaleY,
+	}
+	draw.BiLinear.Transform(dst, s2d, img, b, draw.Src, nil)
+
+	toArea := func(pt Point) Point {
+		return Point{X: (pt.X - area.TopLeft.X) * scaleX, Y: (pt.Y - area.TopLeft.Y) * scaleY}
+	}
+	annotations := p.AnnotationsFor(ref)
+	for _, a := range annotations {
+		for _, m := range a.Measurements {
+			if !m.Complete() {
+				continue
+			}
+			for _, g := range measurementGraphics(m) {
+				drawGraphic(dst, Graphic{Type: "POLYLINE", Points: g.Outline()}, toArea)
+			}
+		}
+		for _, g := range a.Graphics {
+			if !g.Display {
+				drawGraphic(dst, g, toArea)
+			}
+		}
+	}
+
+	t := Transform{Flip: p.Flip, Rotation: p.Rotation}
+	dst = t.Apply(dst)
+	size := dst.Bounds().Size()
+	// text is kept upright, so it is drawn after rotating at the new position of its anchor
+	toImage := func(pt Point) Point {
+		return t.Point(toArea(pt), w, h)
+	}
+	toDisplay := func(pt Point) Point {
+		return Point{X: pt.X * float64(size.X), Y: pt.Y * float64(size.Y)}
+	}
+	for _, a := range annotations {
+		for _, m := range a.Measurements {
+			if m.Complete() {
+				drawText(dst, m.Label(cal), toImage(m.Points[len(m.Points)-1]), true)
+			}
+		}
+		for _, g := range a.Graphics {
+			if g.Display {
+				drawGraphic(dst, g, toDisplay)
+			}
+		}
+		for _, t := range a.Texts {
+			if t.Display {
+				drawText(dst, t.Text, toDisplay(t.Anchor), false)
+			} else {
+				drawText(dst, t.Text, toImage(t.Anchor), false)
+			}
+		}
+	}
+	return dst
+}
+
+func drawGraphic(dst *image.RGBA, g Graphic, transform func(Point) Point) {
+	outline := g.Outline()
+	points := make([]Point, len(outline))
+	for i, p := range outline {
+		points[i] = transform(p)
+	}
+
+	size := dst.Bounds().Size()
+	src := image.NewUniform(AnnotationColor)
+	if g.Filled && len(points) > 2 {
+		r := vector.NewRasterizer(size.X, size.Y)
+		r.MoveTo(float32(points[0].X), float32(points[0].Y))
+		for _, p := range points[1:] {
+			r.LineTo(float32(p.X), float32(p.Y))
+		}
+		r.ClosePath()
+		r.Draw(dst, dst.Bounds(), src, image.Point{})
+		return
+	}
+
+	if g.Type == "POINT" || len(points) == 1 {
+		for _, p := range points {
+			strokeLine(dst, src, Point{X: p.X - 1, Y: p.Y}, Point{X: p.X + 1, Y: p.Y}, annotationLineWidth*2)
+		}
+		return
+	}
+	for i := 1; i < len(points); i++ {
+		strokeLine(dst, src, points[i-1], points[i], annotationLineWidth)
+	}
+}
+
+// strokeLine draws a single segment, using its own rasterizer so overlapping lines do not cancel out.
+func strokeLine(dst *image.RGBA, src image.Image, a, b Point, width float64) {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		dx, length = 1, 1
+	}
+	nx, ny := -dy/length*width/2, dx/length*width/2
+
+	size := dst.Bounds().Size()
+	r := vector.NewRasterizer(size.X, size.Y)
+	r.MoveTo(float32(a.X+nx), float32(a.Y+ny))
+	r.LineTo(float32(b.X+nx), float32(b.Y+ny))
+	r.LineTo(float32(b.X-nx), float32(b.Y-ny))
+	r.LineTo(float32(a.X-nx), float32(a.Y-ny))
+	r.ClosePath()
+	r.Draw(dst, dst.Bounds(), src, image.Point{})
+}
+
+// drawText writes a line of text with its top left corner at pos, or offset from it for measurement labels.
+func drawText(dst *image.RGBA, text string, pos Point, offset bool) {
+	if text == "" {
+		return
+	}
+	if offset {
+		pos = Point{X: pos.X + labelOffset, Y: pos.Y + labelOffset}
+	}
+
+	face := basicfont.Face7x13
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(AnnotationColor), Face: face,
+		Dot: fixed.P(int(pos.X), int(pos.Y)+face.Ascent)}
+	d.DrawString(asciiLabels.Replace(text))
+}
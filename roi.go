@@ -0,0 +1,160 @@
+package dicomgraphics
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Statistics summarises the modality values of the pixels inside a region.
+type Statistics struct {
+	Mean, StdDev, Min, Max float64
+	// Count is the number of pixels inside the region.
+	Count int
+	// Area is the size of the region in mm², or in pixels if the image is not calibrated.
+	// For a region measurement it is the area inside the outline, as in the label of the measurement.
+	Area float64
+}
+
+// String returns the value statistics on a single line.
+func (s Statistics) String() string {
+	return fmt.Sprintf("Mean %.1f SD %.1f Min %.1f Max %.1f Count %d", s.Mean, s.StdDev, s.Min, s.Max, s.Count)
+}
+
+// Statistics computes the modality values of the pixels where mask is opaque.
+// Area is calculated from the pixel count using cal. The standard deviation is that of the population.
+func (d *DICOMImage) Statistics(mask image.Image, cal Calibration) Statistics {
+	stats := Statistics{Min: math.Inf(1), Max: math.Inf(-1)}
+	b := mask.Bounds().Intersect(d.Bounds())
+
+	sum, sumSq := 0.0, 0.0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := mask.At(x, y).RGBA(); a < 0x8000 {
+				continue
+			}
+
+			val, ok := d.ModalityValue(x, y)
+			if !ok {
+				continue
+			}
+			stats.Count++
+			sum += val
+			sumSq += val * val
+			stats.Min = math.Min(stats.Min, val)
+			stats.Max = math.Max(stats.Max, val)
+		}
+	}
+
+	if stats.Count == 0 {
+		return Statistics{}
+	}
+	n := float64(stats.Count)
+	stats.Mean = sum / n
+	stats.StdDev = math.Sqrt(math.Max(0, sumSq/n-stats.Mean*stats.Mean))
+	stats.Area = n
+	if cal.Valid() {
+		stats.Area *= cal.RowSpacing * cal.ColumnSpacing
+	}
+	return stats
+}
+
+// RegionStatistics computes the statistics of the pixels inside a region measurement.
+// The returned bool is false if the measurement is not a complete region.
+func (d *DICOMImage) RegionStatistics(m *Measurement, cal Calibration) (Statistics, bool) {
+	mask := m.Mask(m.Bounds().Intersect(d.Bounds()))
+	if mask == nil {
+		return Statistics{}, false
+	}
+
+	stats := d.Statistics(mask, cal)
+	stats.Area = m.Value(cal)
+	return stats, true
+}
+
+// Mask returns the pixels within bounds that are inside a region measurement.
+// A pixel is inside if its centre is within the shape. Nil is returned if the measurement is not a complete region.
+func (m *Measurement) Mask(bounds image.Rectangle) *image.Alpha {
+	if !m.IsRegion() || !m.Complete() {
+		return nil
+	}
+
+	mask := image.NewAlpha(bounds)
+	inside := regionTest(m.Kind, m.Points)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if inside(float64(x)+0.5, float64(y)+0.5) {
+				mask.SetAlpha(x, y, color.Alpha{A: 0xff})
+			}
+		}
+	}
+	return mask
+}
+
+// Bounds returns the smallest rectangle of whole pixels containing all of the measurement points.
+func (m *Measurement) Bounds() image.Rectangle {
+	if len(m.Points) == 0 {
+		return image.Rectangle{}
+	}
+
+	minX, minY, maxX, maxY := m.Points[0].X, m.Points[0].Y, m.Points[0].X, m.Points[0].Y
+	for _, p := range m.Points[1:] {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	return image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+}
+
+func regionTest(kind MeasurementKind, p []Point) func(x, y float64) bool {
+	switch kind {
+	case MeasureEllipse:
+		cx, cy := (p[0].X+p[1].X)/2, (p[0].Y+p[1].Y)/2
+		rx, ry := math.Abs(p[1].X-p[0].X)/2, math.Abs(p[1].Y-p[0].Y)/2
+		return func(x, y float64) bool {
+			if rx == 0 || ry == 0 {
+				return false
+			}
+			dx, dy := (x-cx)/rx, (y-cy)/ry
+			return dx*dx+dy*dy <= 1
+		}
+	case MeasureFreehand:
+		return func(x, y float64) bool {
+			inside := false
+			for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+				if (p[i].Y > y) != (p[j].Y > y) &&
+					x < (p[j].X-p[i].X)*(y-p[i].Y)/(p[j].Y-p[i].Y)+p[i].X {
+					inside = !inside
+				}
+			}
+			return inside
+		}
+	default:
+		minX, maxX := math.Min(p[0].X, p[1].X), math.Max(p[0].X, p[1].X)
+		minY, maxY := math.Min(p[0].Y, p[1].Y), math.Max(p[0].Y, p[1].Y)
+		return func(x, y float64) bool {
+			return x >= minX && x <= maxX && y >= minY && y <= maxY
+		}
+	}
+}
+
+// regionArea returns the geometric area enclosed by the region outline.
+func regionArea(kind MeasurementKind, p []Point, cal Calibration) float64 {
+	scaleX, scaleY := 1.0, 1.0
+	if cal.Valid() {
+		scaleX, scaleY = cal.ColumnSpacing, cal.RowSpacing
+	}
+
+	switch kind {
+	case MeasureEllipse:
+		return math.Pi * math.Abs(p[1].X-p[0].X) / 2 * scaleX * math.Abs(p[1].Y-p[0].Y) / 2 * scaleY
+	case MeasureFreehand:
+		area := 0.0
+		for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+			area += p[j].X*p[i].Y - p[i].X*p[j].Y
+		}
+		return math.Abs(area) / 2 * scaleX * scaleY
+	default:
+		return math.Abs(p[1].X-p[0].X) * scaleX * math.Abs(p[1].Y-p[0].Y) * scaleY
+	}
+}
@@ -0,0 +1,177 @@
+package dicomgraphics
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/suyashkumar/dicom/pkg/frame"
+)
+
+// testFrame returns a single sample frame with the given rows of stored values.
+func testFrame(rows ...[]int) *frame.NativeFrame {
+	f := &frame.NativeFrame{Rows: len(rows), Cols: len(rows[0]), BitsPerSample: 16}
+	for _, row := range rows {
+		for _, val := range row {
+			f.Data = append(f.Data, []int{val})
+		}
+	}
+	return f
+}
+
+// rectMask returns a mask that is opaque inside r.
+func rectMask(bounds, r image.Rectangle) *image.Alpha {
+	mask := image.NewAlpha(bounds)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			mask.Pix[mask.PixOffset(x, y)] = 0xff
+		}
+	}
+	return mask
+}
+
+func TestStatistics(t *testing.T) {
+	pixels := testFrame(
+		[]int{1, 2, 3, 4},
+		[]int{5, 6, 7, 8},
+		[]int{9, 10, 11, 12},
+		[]int{13, 14, 15, 16})
+	all := image.Rect(0, 0, 4, 4)
+
+	for _, tt := range []struct {
+		name             string
+		pixels           *frame.NativeFrame
+		mask             image.Image
+		slope, intercept float64
+		bits             int
+		signed           bool
+		cal              Calibration
+		want             Statistics
+	}{
+		{"whole image", pixels, rectMask(all, all), 1, 0, 0, false, Calibration{},
+			Statistics{Mean: 8.5, StdDev: math.Sqrt(21.25), Min: 1, Max: 16, Count: 16, Area: 16}},
+		{"centre", pixels, rectMask(all, image.Rect(1, 1, 3, 3)), 1, 0, 0, false, Calibration{},
+			Statistics{Mean: 8.5, StdDev: math.Sqrt(4.25), Min: 6, Max: 11, Count: 4, Area: 4}},
+		{"rescaled", pixels, rectMask(all, image.Rect(0, 0, 2, 1)), 2, -10, 0, false, Calibration{},
+			Statistics{Mean: -7, StdDev: 1, Min: -8, Max: -6, Count: 2, Area: 2}},
+		{"calibrated area", pixels, rectMask(all, image.Rect(0, 0, 2, 1)), 1, 0, 0, false,
+			Calibration{RowSpacing: 0.5, ColumnSpacing: 2}, Statistics{Mean: 1.5, StdDev: 0.5, Min: 1, Max: 2, Count: 2, Area: 2}},
+		{"mask outside image", pixels, rectMask(image.Rect(2, 2, 6, 6), image.Rect(3, 3, 6, 6)), 1, 0, 0, false,
+			Calibration{}, Statistics{Mean: 16, Min: 16, Max: 16, Count: 1, Area: 1}},
+		{"empty mask", pixels, image.NewAlpha(all), 1, 0, 0, false, Calibration{}, Statistics{}},
+		{"unsigned 16 bit", testFrame([]int{40000, 60000}), rectMask(all, all), 1, 0, 16, false, Calibration{},
+			Statistics{Mean: 50000, StdDev: 10000, Min: 40000, Max: 60000, Count: 2, Area: 2}},
+		// the top bits hold an embedded overlay and are not part of the value
+		{"overlay bits", testFrame([]int{0x8000 | 100, 300}), rectMask(all, all), 1, 0, 12, false, Calibration{},
+			Statistics{Mean: 200, StdDev: 100, Min: 100, Max: 300, Count: 2, Area: 2}},
+		{"signed 12 bit", testFrame([]int{0xfff, 1}), rectMask(all, all), 1, 0, 12, true, Calibration{},
+			Statistics{Mean: 0, StdDev: 1, Min: -1, Max: 1, Count: 2, Area: 2}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			img := NewDICOMImage(tt.pixels, 0, 1)
+			img.SetRescale(tt.slope, tt.intercept)
+			img.SetBitsStored(tt.bits, tt.signed)
+
+			got := img.Statistics(tt.mask, tt.cal)
+			if got.Count != tt.want.Count || !closeTo(got.Mean, tt.want.Mean) || !closeTo(got.StdDev, tt.want.StdDev) ||
+				!closeTo(got.Min, tt.want.Min) || !closeTo(got.Max, tt.want.Max) || !closeTo(got.Area, tt.want.Area) {
+				t.Errorf("Statistics() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMask(t *testing.T) {
+	bounds := image.Rect(0, 0, 6, 6)
+	for _, tt := range []struct {
+		name string
+		m    Measurement
+		// want lists the rows of the mask, with '#' where a pixel is inside
+		want []string
+	}{
+		{"rectangle", Measurement{MeasureRectangle, []Point{{1, 1}, {4, 3}}},
+			[]string{"......", ".###..", ".###..", "......", "......", "......"}},
+		{"reversed rectangle", Measurement{MeasureRectangle, []Point{{4, 3}, {1, 1}}},
+			[]string{"......", ".###..", ".###..", "......", "......", "......"}},
+		{"ellipse", Measurement{MeasureEllipse, []Point{{0, 0}, {6, 6}}},
+			[]string{".####.", "######", "######", "######", "######", ".####."}},
+		{"freehand triangle", Measurement{MeasureFreehand, []Point{{0, 0}, {6, 0}, {0, 6}}},
+			[]string{"#####.", "####..", "###...", "##....", "#.....", "......"}},
+		{"flat ellipse", Measurement{MeasureEllipse, []Point{{0, 3}, {6, 3}}}, nil},
+		{"incomplete freehand", Measurement{MeasureFreehand, []Point{{0, 0}, {6, 0}}}, nil},
+		{"ruler", Measurement{MeasureRuler, []Point{{0, 0}, {6, 6}}}, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			mask := tt.m.Mask(bounds)
+			if tt.want == nil {
+				for _, a := range maskPix(mask) {
+					if a != 0 {
+						t.Fatalf("Mask() has pixels inside, want none")
+					}
+				}
+				return
+			}
+			if mask == nil {
+				t.Fatal("Mask() = nil")
+			}
+
+			for y, row := range tt.want {
+				got := []byte(row)
+				for x := range got {
+					got[x] = '.'
+					if mask.AlphaAt(x, y).A != 0 {
+						got[x] = '#'
+					}
+				}
+				if string(got) != row {
+					t.Errorf("row %d = %s, want %s", y, got, row)
+				}
+			}
+		})
+	}
+}
+
+func TestRegionArea(t *testing.T) {
+	pixels := testFrame(make([]int, 10), make([]int, 10), make([]int, 10), make([]int, 10))
+	for _, tt := range []struct {
+		name  string
+		m     Measurement
+		cal   Calibration
+		want  float64
+		count int
+	}{
+		{"rectangle", Measurement{MeasureRectangle, []Point{{1, 1}, {4, 3}}}, Calibration{}, 6, 6},
+		{"calibrated rectangle", Measurement{MeasureRectangle, []Point{{1, 1}, {4, 3}}},
+			Calibration{RowSpacing: 2, ColumnSpacing: 0.5}, 6, 6},
+		{"ellipse", Measurement{MeasureEllipse, []Point{{0, 0}, {4, 4}}}, Calibration{}, 4 * math.Pi, 12},
+		{"freehand", Measurement{MeasureFreehand, []Point{{0, 0}, {4, 0}, {4, 2}, {0, 2}}}, Calibration{}, 8, 8},
+		// the area is that of the outline, even where it extends beyond the image
+		{"outside image", Measurement{MeasureRectangle, []Point{{8, 2}, {12, 6}}}, Calibration{}, 16, 4},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, ok := NewDICOMImage(pixels, 0, 1).RegionStatistics(&tt.m, tt.cal)
+			if !ok {
+				t.Fatal("RegionStatistics() is not a region")
+			}
+			if !closeTo(stats.Area, tt.want) || stats.Count != tt.count {
+				t.Errorf("RegionStatistics() area %v count %d, want %v count %d", stats.Area, stats.Count, tt.want, tt.count)
+			}
+		})
+	}
+
+	if _, ok := NewDICOMImage(pixels, 0, 1).RegionStatistics(&Measurement{MeasureAngle,
+		[]Point{{0, 0}, {1, 1}, {2, 0}}}, Calibration{}); ok {
+		t.Error("RegionStatistics() of an angle is a region")
+	}
+}
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func maskPix(mask *image.Alpha) []uint8 {
+	if mask == nil {
+		return nil
+	}
+	return mask.Pix
+}
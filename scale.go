@@ -0,0 +1,43 @@
+package dicomgraphics
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+// FitSize returns the largest size with the aspect ratio of w by h that fits within maxWidth by maxHeight,
+// along with the scale applied. Images are only ever reduced, a maximum of 0 or less means no limit.
+func FitSize(w, h, maxWidth, maxHeight int) (int, int, float64) {
+	scale := 1.0
+	if maxWidth > 0 && w > maxWidth {
+		scale = float64(maxWidth) / float64(w)
+	}
+	if maxHeight > 0 && h > maxHeight {
+		scale = math.Min(scale, float64(maxHeight)/float64(h))
+	}
+	if scale == 1 {
+		return w, h, 1
+	}
+
+	return int(math.Max(1, math.Round(float64(w)*scale))), int(math.Max(1, math.Round(float64(h)*scale))), scale
+}
+
+// ScaleToFit reduces an image to fit within maxWidth by maxHeight, using Catmull-Rom resampling for quality.
+// The image is returned unchanged with a scale of 1 if it already fits.
+// A 16 bit greyscale image is scaled to another, so that no precision is lost, other images are scaled to RGBA.
+func ScaleToFit(src image.Image, maxWidth, maxHeight int) (image.Image, float64) {
+	b := src.Bounds()
+	w, h, scale := FitSize(b.Dx(), b.Dy(), maxWidth, maxHeight)
+	if scale == 1 {
+		return src, 1
+	}
+
+	var dst draw.Image = image.NewRGBA(image.Rect(0, 0, w, h))
+	if _, ok := src.(*image.Gray16); ok {
+		dst = image.NewGray16(image.Rect(0, 0, w, h))
+	}
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+	return dst, scale
+}
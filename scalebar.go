@@ -0,0 +1,62 @@
+package dicomgraphics
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+)
+
+const (
+	// ScaleBarFraction is the longest a scale bar can be, as a fraction of the image width.
+	ScaleBarFraction = 0.25
+	// ScaleBarTick is the height of the ticks at each end of a scale bar, in pixels.
+	ScaleBarTick = 4
+)
+
+// ScaleBar returns the displayed length and label, such as "5 cm", of the longest round distance that fits in maxLength.
+// The spacing is the width in mm of each displayed pixel, if it is not positive false is returned.
+func ScaleBar(spacing, maxLength float64) (float64, string, bool) {
+	if spacing <= 0 || maxLength <= 0 {
+		return 0, "", false
+	}
+
+	maxMM := maxLength * spacing
+	magnitude := math.Pow(10, math.Floor(math.Log10(maxMM)))
+	mm := magnitude
+	for _, step := range []float64{5, 2} {
+		if step*magnitude <= maxMM {
+			mm = step * magnitude
+			break
+		}
+	}
+
+	label := formatDecimal(mm) + " mm"
+	if mm >= 10 {
+		label = formatDecimal(mm/10) + " cm"
+	}
+	return mm / spacing, label, true
+}
+
+// DrawScaleBar burns a labelled scale bar into the bottom centre of an image, above any orientation marker.
+// The spacing is the width in mm of each pixel of dst, nothing is drawn if it is not positive.
+func DrawScaleBar(dst *image.RGBA, spacing float64) {
+	b := dst.Bounds()
+	length, label, ok := ScaleBar(spacing, float64(b.Dx())*ScaleBarFraction)
+	if !ok {
+		return
+	}
+
+	face := basicfont.Face7x13
+	lineHeight := face.Metrics().Height.Ceil()
+	y := float64(b.Max.Y - CornerMargin - lineHeight - ScaleBarTick)
+	left := float64(b.Min.X+b.Max.X)/2 - length/2
+	right := left + length
+	drawGraphic(dst, Graphic{Type: "POLYLINE", Points: []Point{
+		{X: left, Y: y - ScaleBarTick}, {X: left, Y: y}, {X: right, Y: y}, {X: right, Y: y - ScaleBarTick}}},
+		func(p Point) Point { return p })
+
+	width := font.MeasureString(face, label).Ceil()
+	drawText(dst, label, Point{X: (left+right)/2 - float64(width)/2, Y: y - ScaleBarTick - float64(lineHeight)}, false)
+}
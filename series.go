@@ -0,0 +1,95 @@
+package dicomgraphics
+
+import (
+	"math"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// minParallelCosine is how closely the normals of two planes must align, about 8 degrees, for them to be matched.
+const minParallelCosine = 0.99
+
+// Slice is a single image frame along with the dataset it was loaded from.
+type Slice struct {
+	Data  *dicom.Dataset
+	Frame *frame.NativeFrame
+	Plane ImagePlane
+	// Index is the number of this frame within its dataset, starting at 0.
+	Index int
+}
+
+// Series is an ordered stack of slices, such as those scrolled through in a viewer.
+type Series struct {
+	Slices []*Slice
+}
+
+// NewSeries creates a series containing every native frame of the given datasets.
+func NewSeries(data ...*dicom.Dataset) *Series {
+	s := &Series{}
+	for _, d := range data {
+		s.Add(d)
+	}
+	return s
+}
+
+// Add appends the frames of a dataset to the series and returns how many were found.
+func (s *Series) Add(data *dicom.Dataset) int {
+	elem, err := data.FindElementByTag(tag.PixelData)
+	if err != nil {
+		return 0
+	}
+	info, ok := elem.Value.GetValue().(dicom.PixelDataInfo)
+	if !ok {
+		return 0
+	}
+
+	planes := ImagePlanes(data, len(info.Frames))
+	count := 0
+	for i, f := range info.Frames {
+		if f.Encapsulated {
+			continue
+		}
+
+		s.Slices = append(s.Slices, &Slice{Data: data, Frame: &f.NativeData, Plane: planes[i], Index: i})
+		count++
+	}
+	return count
+}
+
+// Len returns the number of slices in the series.
+func (s *Series) Len() int {
+	return len(s.Slices)
+}
+
+// Nearest returns the index of the slice closest to the given plane, considering only slices that are parallel to it.
+// If the plane is not valid, has no frame of reference or no slice shares it then -1 is returned.
+func (s *Series) Nearest(plane ImagePlane) int {
+	if !plane.Valid() || plane.FrameOfReference == "" {
+		return -1
+	}
+
+	n := plane.Normal()
+	best, bestDist := -1, math.MaxFloat64
+	for i, slice := range s.Slices {
+		p := slice.Plane
+		if !p.Valid() || p.FrameOfReference != plane.FrameOfReference || !parallel(n, p.Normal()) {
+			continue
+		}
+
+		dist := math.Abs((p.Position[0]-plane.Position[0])*n[0] +
+			(p.Position[1]-plane.Position[1])*n[1] + (p.Position[2]-plane.Position[2])*n[2])
+		if dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+// parallel returns true if two normals point along the same line, in either direction.
+func parallel(a, b [3]float64) bool {
+	dot := a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+	length := math.Sqrt((a[0]*a[0] + a[1]*a[1] + a[2]*a[2]) * (b[0]*b[0] + b[1]*b[1] + b[2]*b[2]))
+	return length > 0 && math.Abs(dot) >= minParallelCosine*length
+}
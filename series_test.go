@@ -0,0 +1,50 @@
+package dicomgraphics
+
+import "testing"
+
+// testPlane returns a valid plane at the given position with the given row and column directions.
+func testPlane(frameOfReference string, position [3]float64, orientation [6]float64) ImagePlane {
+	return ImagePlane{Position: position, Orientation: orientation, Spacing: [2]float64{1, 1},
+		FrameOfReference: frameOfReference, valid: true}
+}
+
+func TestSeriesNearest(t *testing.T) {
+	axial := [6]float64{1, 0, 0, 0, 1, 0}
+	sagittal := [6]float64{0, 1, 0, 0, 0, -1}
+	series := &Series{}
+	for _, z := range []float64{0, 5, 10, 15} {
+		series.Slices = append(series.Slices, &Slice{Plane: testPlane("1.2.3", [3]float64{0, 0, z}, axial)})
+	}
+
+	for _, tt := range []struct {
+		name   string
+		series *Series
+		plane  ImagePlane
+		want   int
+	}{
+		{"same position", series, testPlane("1.2.3", [3]float64{0, 0, 10}, axial), 2},
+		{"between slices", series, testPlane("1.2.3", [3]float64{30, -20, 6}, axial), 1},
+		{"beyond the stack", series, testPlane("1.2.3", [3]float64{0, 0, 100}, axial), 3},
+		{"reversed normal", series, testPlane("1.2.3", [3]float64{0, 0, 14}, [6]float64{0, 1, 0, 1, 0, 0}), 3},
+		{"slightly tilted", series, testPlane("1.2.3", [3]float64{0, 0, 5}, [6]float64{1, 0, 0, 0, 0.995, 0.0998}), 1},
+		{"perpendicular", series, testPlane("1.2.3", [3]float64{0, 0, 5}, sagittal), -1},
+		{"oblique", series, testPlane("1.2.3", [3]float64{0, 0, 5}, [6]float64{1, 0, 0, 0, 0.707, 0.707}), -1},
+		{"other frame of reference", series, testPlane("4.5.6", [3]float64{0, 0, 5}, axial), -1},
+		{"no frame of reference", series, testPlane("", [3]float64{0, 0, 5}, axial), -1},
+		{"no frame of reference in series",
+			&Series{Slices: []*Slice{{Plane: testPlane("", [3]float64{}, axial)}}},
+			testPlane("", [3]float64{}, axial), -1},
+		{"invalid plane", series, ImagePlane{FrameOfReference: "1.2.3", Orientation: axial}, -1},
+		{"no orientation", series, testPlane("1.2.3", [3]float64{0, 0, 5}, [6]float64{}), -1},
+		{"mixed series", &Series{Slices: []*Slice{
+			{Plane: testPlane("1.2.3", [3]float64{0, 0, 5}, sagittal)},
+			{Plane: testPlane("1.2.3", [3]float64{0, 0, 20}, axial)},
+		}}, testPlane("1.2.3", [3]float64{0, 0, 5}, axial), 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.series.Nearest(tt.plane); got != tt.want {
+				t.Errorf("Nearest() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package dicomgraphics
+
+import (
+	"image"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/frame"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// Shutter hides the parts of an image outside of its shapes, such as a collimator border.
+// When several shapes are set only the area inside all of them is shown.
+type Shutter struct {
+	// Rectangular is set when the edges define a rectangle of visible columns and rows, starting at 1.
+	Rectangular               bool
+	Left, Right, Upper, Lower int
+
+	// Circular is set when only the pixels within Radius of Centre are visible.
+	Circular bool
+	Centre   Point
+	Radius   float64
+
+	// Vertices is the outline of a polygonal shutter, or empty if there is none.
+	Vertices []Point
+
+	// Bitmap holds the pixels hidden by a bitmap shutter, which is stored as an overlay plane, or nil.
+	Bitmap *image.Alpha
+
+	// Value is the grey level, from 0 to 0xffff, used to paint the hidden area.
+	Value uint16
+}
+
+// ParseShutter reads the Display Shutter and Bitmap Display Shutter modules of a presentation state, or of an image
+// without a bitmap shutter that differs by frame. Nil is returned if no shutter is specified.
+func ParseShutter(data *dicom.Dataset) *Shutter {
+	return ParseFrameShutter(data, 0, nil)
+}
+
+// ParseFrameShutter reads the shutter of a frame of an image, starting at 0.
+// The pixels of the frame are needed to read a bitmap shutter embedded in the high bits of the Pixel Data.
+func ParseFrameShutter(data *dicom.Dataset, frameIndex int, pixels *frame.NativeFrame) *Shutter {
+	elems := data.Elements
+	s := &Shutter{}
+	for _, shape := range elementStrings(findElement(elems, tag.ShutterShape)) {
+		switch strings.TrimSpace(shape) {
+		case "RECTANGULAR":
+			left := elementFloats(findElement(elems, tag.ShutterLeftVerticalEdge))
+			right := elementFloats(findElement(elems, tag.ShutterRightVerticalEdge))
+			upper := elementFloats(findElement(elems, tag.ShutterUpperHorizontalEdge))
+			lower := elementFloats(findElement(elems, tag.ShutterLowerHorizontalEdge))
+			if len(left) > 0 && len(right) > 0 && len(upper) > 0 && len(lower) > 0 {
+				s.Rectangular = true
+				s.Left, s.Right, s.Upper, s.Lower = int(left[0]), int(right[0]), int(upper[0]), int(lower[0])
+			}
+		case "CIRCULAR":
+			centre := elementFloats(findElement(elems, tag.CenterOfCircularShutter))
+			radius := elementFloats(findElement(elems, tag.RadiusOfCircularShutter))
+			if len(centre) == 2 && len(radius) > 0 {
+				s.Circular = true
+				s.Centre = Point{X: centre[1], Y: centre[0]}
+				s.Radius = radius[0]
+			}
+		case "POLYGONAL":
+			vertices := elementFloats(findElement(elems, tag.VerticesOfThePolygonalShutter))
+			for i := 0; i+1 < len(vertices); i += 2 {
+				s.Vertices = append(s.Vertices, Point{X: vertices[i+1], Y: vertices[i]})
+			}
+		case "BITMAP":
+			if o := shutterOverlay(data); o != nil {
+				s.Bitmap = o.Mask(frameIndex, pixels)
+			}
+		}
+	}
+	if !s.Rectangular && !s.Circular && len(s.Vertices) < 3 && s.Bitmap == nil {
+		return nil
+	}
+
+	if value := elementFloats(findElement(elems, tag.ShutterPresentationValue)); len(value) > 0 {
+		s.Value = uint16(value[0])
+	}
+	return s
+}
+
+// Visible returns true if the pixel at the given image coordinate is not hidden by the shutter.
+func (s *Shutter) Visible(x, y int) bool {
+	// shutter coordinates count columns and rows from 1
+	col, row := float64(x+1), float64(y+1)
+	if s.Rectangular && (x+1 < s.Left || x+1 > s.Right || y+1 < s.Upper || y+1 > s.Lower) {
+		return false
+	}
+	if s.Circular {
+		dx, dy := col-s.Centre.X, row-s.Centre.Y
+		if dx*dx+dy*dy > s.Radius*s.Radius {
+			return false
+		}
+	}
+	if len(s.Vertices) >= 3 && !regionTest(MeasureFreehand, s.Vertices)(col, row) {
+		return false
+	}
+	if s.Bitmap != nil && s.Bitmap.AlphaAt(x, y).A != 0 {
+		return false
+	}
+
+	return true
+}
+
+// shutterOverlay returns the overlay plane used as a bitmap shutter, or nil if there is none.
+func shutterOverlay(data *dicom.Dataset) *Overlay {
+	group := TagFloats(data, tag.ShutterOverlayGroup)
+	if len(group) == 0 {
+		return nil
+	}
+
+	for _, o := range parseOverlays(data) {
+		if int(o.Group) == int(group[0]) {
+			return o
+		}
+	}
+	return nil
+}
+
+// isBitmapShutter returns true if the overlay group is used as a bitmap shutter rather than being displayed.
+func isBitmapShutter(data *dicom.Dataset, group uint16) bool {
+	shutter := TagFloats(data, tag.ShutterOverlayGroup)
+	if len(shutter) == 0 || int(shutter[0]) != int(group) {
+		return false
+	}
+
+	for _, shape := range elementStrings(findElement(data.Elements, tag.ShutterShape)) {
+		if strings.TrimSpace(shape) == "BITMAP" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package dicomgraphics
+
+import (
+	"image"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+	"golang.org/x/image/draw"
+)
+
+// Study is a group of series that share a Study Instance UID.
+type Study struct {
+	// Date is the Study Date formatted as YYYY-MM-DD.
+	UID, Description, Date string
+	// PatientName and PatientID identify who the study belongs to.
+	PatientName, PatientID string
+
+	Series []*StudySeries
+}
+
+// StudySeries is a series within a study, with the details used to choose it from a list.
+type StudySeries struct {
+	UID, Description, Modality string
+	Number                     int
+
+	*Series
+}
+
+// GroupStudies sorts the images in a list of datasets into studies and series.
+// Studies are ordered by date, series by Series Number and slices by Instance Number,
+// or by their position along the slice normal if the numbers are the same or missing.
+// Datasets without native pixel data are ignored.
+func GroupStudies(data []*dicom.Dataset) []*Study {
+	var studies []*Study
+	studyByUID := make(map[string]*Study)
+	datasets := make(map[*StudySeries][]*dicom.Dataset)
+	seriesByUID := make(map[string]*StudySeries)
+	for _, d := range data {
+		if _, err := d.FindElementByTag(tag.PixelData); err != nil {
+			continue
+		}
+
+		studyUID := TagString(d, tag.StudyInstanceUID)
+		study, ok := studyByUID[studyUID]
+		if !ok {
+			study = &Study{UID: studyUID, Description: TagString(d, tag.StudyDescription),
+				Date: formatDate(TagString(d, tag.StudyDate)), PatientName: formatPersonName(TagString(d, tag.PatientName)),
+				PatientID: TagString(d, tag.PatientID)}
+			studyByUID[studyUID] = study
+			studies = append(studies, study)
+		}
+
+		seriesUID := TagString(d, tag.SeriesInstanceUID)
+		series, ok := seriesByUID[studyUID+"/"+seriesUID]
+		if !ok {
+			series = &StudySeries{UID: seriesUID, Description: TagString(d, tag.SeriesDescription),
+				Modality: TagString(d, tag.Modality), Number: tagInt(d, tag.SeriesNumber), Series: &Series{}}
+			seriesByUID[studyUID+"/"+seriesUID] = series
+			study.Series = append(study.Series, series)
+		}
+		datasets[series] = append(datasets[series], d)
+	}
+
+	for _, study := range studies {
+		for _, series := range study.Series {
+			images := datasets[series]
+			sortImages(images)
+			for _, d := range images {
+				series.Add(d)
+			}
+		}
+		sort.SliceStable(study.Series, func(i, j int) bool {
+			return study.Series[i].Number < study.Series[j].Number
+		})
+	}
+	sort.SliceStable(studies, func(i, j int) bool {
+		return studies[i].Date < studies[j].Date
+	})
+	return studies
+}
+
+// sortImages orders the images of a series by Instance Number, and those with the same number by position.
+func sortImages(images []*dicom.Dataset) {
+	numbers := make(map[*dicom.Dataset]int, len(images))
+	planes := make(map[*dicom.Dataset]ImagePlane, len(images))
+	for _, d := range images {
+		numbers[d] = tagInt(d, tag.InstanceNumber)
+		planes[d] = ImagePlanes(d, 1)[0]
+	}
+
+	sort.SliceStable(images, func(i, j int) bool {
+		a, b := images[i], images[j]
+		if numbers[a] != numbers[b] {
+			return numbers[a] < numbers[b]
+		}
+		if !planes[a].Valid() || !planes[b].Valid() {
+			return false
+		}
+		return planes[a].SliceLocation() < planes[b].SliceLocation()
+	})
+}
+
+// Thumbnail draws a slice at its default window, scaled to fit within a square of the given size.
+// If the dataset has no window then the full range of values in the slice is shown.
+func Thumbnail(slice *Slice, size int) *image.RGBA {
+	img := NewDICOMImage(slice.Frame, 0, 0)
+	img.SetRescale(Rescale(slice.Data))
+	img.SetBitsStored(BitsStored(slice.Data))
+	if level, width, ok := DefaultWindow(slice.Data); ok {
+		img.SetWindowLevel(level)
+		img.SetWindowWidth(width)
+	} else {
+		stats := img.Statistics(image.Opaque, Calibration{})
+		img.SetWindowLevel(ClampInt16((stats.Min + stats.Max) / 2))
+		img.SetWindowWidth(ClampInt16(math.Max(1, stats.Max-stats.Min)))
+	}
+
+	b := img.Bounds()
+	if b.Empty() || size <= 0 {
+		return image.NewRGBA(image.Rectangle{})
+	}
+	scale := math.Min(float64(size)/float64(b.Dx()), float64(size)/float64(b.Dy()))
+	w, h := int(math.Max(1, math.Round(float64(b.Dx())*scale))), int(math.Max(1, math.Round(float64(b.Dy())*scale)))
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// tagInt returns the first value of an integer string tag, or 0 if it is missing.
+func tagInt(data *dicom.Dataset, t tag.Tag) int {
+	i, _ := strconv.Atoi(elementString(findElement(data.Elements, t)))
+	return i
+}
+
+// ClampInt16 rounds a value to the nearest int16, such as a window level or width, clamping it to the 16 bit range.
+func ClampInt16(f float64) int16 {
+	return int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round(f))))
+}
@@ -0,0 +1,154 @@
+package dicomgraphics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+// maxTagValueLength is the longest value shown for a tag before it is shortened.
+const maxTagValueLength = 256
+
+// TagNode describes an element of a dataset for browsing or dumping its contents.
+// Sequences have a child node for each item, whose children are the elements of that item.
+type TagNode struct {
+	Tag tag.Tag
+	// Keyword is the dictionary name of the tag, such as "PatientName", or the item name of a sequence item.
+	Keyword string
+	VR      string
+	Length  uint32
+	// Value is a readable summary of the value, binary data is shown as its size.
+	Value    string
+	Children []*TagNode
+
+	// Item is set for the nodes of sequence items, which do not have a tag.
+	Item bool
+}
+
+// TagTree returns a node for each element of a dataset, in the order they were read.
+func TagTree(data *dicom.Dataset) []*TagNode {
+	return tagNodes(data.Elements)
+}
+
+func tagNodes(elems []*dicom.Element) []*TagNode {
+	nodes := make([]*TagNode, 0, len(elems))
+	for _, elem := range elems {
+		nodes = append(nodes, newTagNode(elem))
+	}
+	return nodes
+}
+
+func newTagNode(elem *dicom.Element) *TagNode {
+	n := &TagNode{Tag: elem.Tag, Keyword: TagKeyword(elem.Tag), VR: elem.RawValueRepresentation, Length: elem.ValueLength}
+	if elem.Value == nil {
+		return n
+	}
+
+	switch val := elem.Value.GetValue().(type) {
+	case []*dicom.SequenceItemValue:
+		for i, item := range sequenceItems(elem) {
+			n.Children = append(n.Children, &TagNode{Keyword: fmt.Sprintf("Item %d", i+1), Item: true,
+				Value: fmt.Sprintf("%d elements", len(item)), Children: tagNodes(item)})
+		}
+		n.Value = fmt.Sprintf("%d items", len(val))
+	case dicom.PixelDataInfo:
+		n.Value = fmt.Sprintf("%d frames", len(val.Frames))
+	case []byte:
+		n.Value = fmt.Sprintf("%d bytes", len(val))
+	default:
+		n.Value = strings.Join(elementStrings(elem), "\\")
+	}
+	if len(n.Value) > maxTagValueLength {
+		n.Value = n.Value[:maxTagValueLength] + "..."
+	}
+	return n
+}
+
+// TagKeyword returns the dictionary keyword of a tag, "PrivateTag" for private tags or "" if it is not known.
+func TagKeyword(t tag.Tag) string {
+	if info, err := tag.Find(t); err == nil {
+		return info.Name
+	}
+	if t.Group%2 == 1 {
+		return "PrivateTag"
+	}
+	return ""
+}
+
+// String returns the node on a single line, as the tag, keyword, VR, length and value.
+func (n *TagNode) String() string {
+	if n.Item {
+		return fmt.Sprintf("%s (%s)", n.Keyword, n.Value)
+	}
+	return fmt.Sprintf("%s %s %s %d [%s]", n.Tag, n.Keyword, n.VR, n.Length, n.Value)
+}
+
+// Matches returns true if the node tag, keyword or value contains the filter text, ignoring case.
+// A filter of four hex digits, optionally in brackets, also matches every tag in that group.
+func (n *TagNode) Matches(filter string) bool {
+	filter = strings.ToLower(strings.TrimSpace(filter))
+	if filter == "" {
+		return true
+	}
+
+	group := strings.Trim(filter, "()")
+	if len(group) == 4 && strings.HasPrefix(n.Tag.String(), "("+group+",") && !n.Item {
+		return true
+	}
+	return strings.Contains(n.Tag.String(), filter) || strings.Contains(strings.ToLower(n.Keyword), filter) ||
+		strings.Contains(strings.ToLower(n.Value), filter)
+}
+
+// FilterTags returns the nodes that match the filter, along with the sequences and items that contain a match.
+// Children of a matching node are all kept.
+func FilterTags(nodes []*TagNode, filter string) []*TagNode {
+	var ret []*TagNode
+	for _, n := range nodes {
+		if n.Matches(filter) {
+			ret = append(ret, n)
+			continue
+		}
+
+		if children := FilterTags(n.Children, filter); len(children) > 0 {
+			parent := *n
+			parent.Children = children
+			ret = append(ret, &parent)
+		}
+	}
+	return ret
+}
+
+// DumpTags writes the nodes one per line, indenting the contents of each sequence.
+func DumpTags(nodes []*TagNode) string {
+	var b strings.Builder
+	dumpNodes(&b, nodes, "")
+	return b.String()
+}
+
+func dumpNodes(b *strings.Builder, nodes []*TagNode, indent string) {
+	for _, n := range nodes {
+		b.WriteString(indent + n.String() + "\n")
+		dumpNodes(b, n.Children, indent+"  ")
+	}
+}
+
+// ParseTag reads a tag given as a dictionary keyword, such as "PatientName", or as hex numbers in the form
+// (gggg,eeee), gggg,eeee or ggggeeee.
+func ParseTag(s string) (tag.Tag, error) {
+	s = strings.TrimSpace(s)
+	digits := strings.Replace(strings.Trim(s, "()"), ",", "", 1)
+	if len(digits) == 8 {
+		if n, err := strconv.ParseUint(digits, 16, 32); err == nil {
+			return tag.Tag{Group: uint16(n >> 16), Element: uint16(n)}, nil
+		}
+	}
+
+	info, err := tag.FindByName(s)
+	if err != nil {
+		return tag.Tag{}, fmt.Errorf("unknown tag %q", s)
+	}
+	return info.Tag, nil
+}
@@ -0,0 +1,167 @@
+package dicomgraphics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/png"
+	"io"
+)
+
+// kittyChunkSize is the largest payload of each escape sequence of the Kitty graphics protocol.
+const kittyChunkSize = 4096
+
+// EncodeHalfBlocks writes an image as lines of text for a terminal with 24 bit ANSI colour.
+// Each character is an upper half block coloured with two pixels, the upper in the foreground and the lower
+// in the background, so an image w pixels wide and h high takes w columns and (h+1)/2 lines.
+func EncodeHalfBlocks(w io.Writer, img image.Image) error {
+	bw := bufio.NewWriter(w)
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		// colours are only written when they change along the line
+		var fg, bg color.RGBA
+		first := true
+		for x := b.Min.X; x < b.Max.X; x++ {
+			top := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			if first || top != fg {
+				fmt.Fprintf(bw, "\x1b[38;2;%d;%d;%dm", top.R, top.G, top.B)
+				fg = top
+			}
+			if y+1 < b.Max.Y {
+				bottom := color.RGBAModel.Convert(img.At(x, y+1)).(color.RGBA)
+				if first || bottom != bg {
+					fmt.Fprintf(bw, "\x1b[48;2;%d;%d;%dm", bottom.R, bottom.G, bottom.B)
+					bg = bottom
+				}
+			}
+			first = false
+			bw.WriteString("▀")
+		}
+		bw.WriteString("\x1b[0m\n")
+	}
+	return bw.Flush()
+}
+
+// EncodeSixel writes an image as a DEC Sixel graphic, shown by terminals such as xterm, mlterm and foot.
+// Greyscale images use 256 levels of grey, colour images are dithered to a palette of 256 colours.
+func EncodeSixel(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	var dst *image.Paletted
+	if isGrey(img) {
+		p := make(color.Palette, 256)
+		for i := range p {
+			p[i] = color.Gray{Y: uint8(i)}
+		}
+		dst = image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), p)
+		draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	} else {
+		dst = image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), palette.Plan9)
+		draw.FloydSteinberg.Draw(dst, dst.Bounds(), img, b.Min)
+	}
+
+	bw := bufio.NewWriter(w)
+	width, height := dst.Rect.Dx(), dst.Rect.Dy()
+	fmt.Fprintf(bw, "\x1bPq\"1;1;%d;%d", width, height)
+	for i, c := range dst.Palette {
+		r, g, bl, _ := c.RGBA()
+		fmt.Fprintf(bw, "#%d;2;%d;%d;%d", i, r*100/0xffff, g*100/0xffff, bl*100/0xffff)
+	}
+
+	// each band of six rows is written once for every colour in it, returning to the start of the band between
+	sixels := make([]byte, width)
+	for y := 0; y < height; y += 6 {
+		var bands [256][]byte
+		var order []uint8
+		for dy := 0; dy < 6 && y+dy < height; dy++ {
+			row := dst.Pix[(y+dy)*dst.Stride:]
+			for x := 0; x < width; x++ {
+				c := row[x]
+				if bands[c] == nil {
+					bands[c] = make([]byte, width)
+					order = append(order, c)
+				}
+				bands[c][x] |= 1 << uint(dy)
+			}
+		}
+
+		for i, c := range order {
+			if i > 0 {
+				bw.WriteByte('$')
+			}
+			fmt.Fprintf(bw, "#%d", c)
+			end := width
+			for end > 0 && bands[c][end-1] == 0 {
+				end--
+			}
+			for x := 0; x < end; x++ {
+				sixels[x] = '?' + bands[c][x]
+			}
+			writeSixelRuns(bw, sixels[:end])
+		}
+		bw.WriteByte('-')
+	}
+	bw.WriteString("\x1b\\")
+	return bw.Flush()
+}
+
+// writeSixelRuns writes a line of sixel characters, compressing repeats of the same character.
+func writeSixelRuns(w *bufio.Writer, sixels []byte) {
+	for i := 0; i < len(sixels); {
+		n := 1
+		for i+n < len(sixels) && sixels[i+n] == sixels[i] {
+			n++
+		}
+		if n > 3 {
+			fmt.Fprintf(w, "!%d%c", n, sixels[i])
+		} else {
+			for j := 0; j < n; j++ {
+				w.WriteByte(sixels[i])
+			}
+		}
+		i += n
+	}
+}
+
+// EncodeKitty writes an image for display by the graphics protocol of the Kitty terminal, also supported by
+// WezTerm and Ghostty. The image is sent as a PNG in chunks of base64 and placed at the cursor.
+// If columns and rows are more than 0 the terminal scales the image to fill that many cells.
+func EncodeKitty(w io.Writer, img image.Image, columns, rows int) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, eightBitImage(img, isGrey(img))); err != nil {
+		return err
+	}
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	bw := bufio.NewWriter(w)
+	for first := true; first || len(data) > 0; first = false {
+		chunk := data
+		if len(chunk) > kittyChunkSize {
+			chunk = chunk[:kittyChunkSize]
+		}
+		data = data[len(chunk):]
+		more := 0
+		if len(data) > 0 {
+			more = 1
+		}
+
+		bw.WriteString("\x1b_G")
+		if first {
+			bw.WriteString("a=T,f=100")
+			if columns > 0 {
+				fmt.Fprintf(bw, ",c=%d", columns)
+			}
+			if rows > 0 {
+				fmt.Fprintf(bw, ",r=%d", rows)
+			}
+			bw.WriteByte(',')
+		}
+		fmt.Fprintf(bw, "m=%d;%s\x1b\\", more, chunk)
+	}
+	bw.WriteString("\n")
+	return bw.Flush()
+}
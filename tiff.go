@@ -0,0 +1,182 @@
+package dicomgraphics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+)
+
+// TIFF field types and tags used by EncodeTIFF, from the TIFF 6.0 baseline.
+const (
+	tiffShort    = 3
+	tiffLong     = 4
+	tiffRational = 5
+
+	tiffNewSubfileType = 254
+	tiffImageWidth     = 256
+	tiffImageLength    = 257
+	tiffBitsPerSample  = 258
+	tiffCompression    = 259
+	tiffPhotometric    = 262
+	tiffStripOffsets   = 273
+	tiffSamples        = 277
+	tiffRowsPerStrip   = 278
+	tiffStripBytes     = 279
+	tiffXResolution    = 282
+	tiffYResolution    = 283
+	tiffResolutionUnit = 296
+	tiffPageNumber     = 297
+
+	// tiffPage marks an image as one page of a multi-page file
+	tiffPage = 2
+)
+
+// tiffEntry is a field of an image file directory, in the order it is written.
+type tiffEntry struct {
+	Tag, Kind uint16
+	Count     uint32
+	Value     uint32
+}
+
+// EncodeTIFF writes images as the pages of an uncompressed baseline TIFF file.
+// Pages are written as 8 or 16 bit greyscale if they are *image.Gray or *image.Gray16, otherwise as 8 bit RGB.
+func EncodeTIFF(w io.Writer, pages []image.Image) error {
+	if len(pages) == 0 {
+		return errors.New("tiff: no pages to write")
+	}
+
+	le := binary.LittleEndian
+	out := bufio.NewWriter(w)
+	offset := uint32(8)
+	for i, page := range pages {
+		pix := tiffPixels(page)
+		bits, samples := tiffFormat(page)
+		dataOffset := offset
+		// each directory follows the pixels of its page
+		ifdOffset := even(dataOffset + uint32(len(pix)))
+		if i == 0 {
+			header := []byte{'I', 'I', 42, 0, 0, 0, 0, 0}
+			le.PutUint32(header[4:], ifdOffset)
+			if _, err := out.Write(header); err != nil {
+				return err
+			}
+		}
+
+		// values that do not fit in an entry follow the directory: the bits of each sample then the resolutions
+		const entryCount = 14
+		extraOffset := ifdOffset + 2 + entryCount*12 + 4
+		var extra bytes.Buffer
+		bitsValue := uint32(bits)
+		if samples > 1 {
+			bitsValue = extraOffset
+			for s := 0; s < samples; s++ {
+				_ = binary.Write(&extra, le, uint16(bits))
+			}
+		}
+		resolutionOffset := extraOffset + uint32(extra.Len())
+		_ = binary.Write(&extra, le, []uint32{72, 1, 72, 1})
+		next := uint32(0)
+		if i < len(pages)-1 {
+			next = even(extraOffset + uint32(extra.Len()) + tiffSize(pages[i+1]))
+		}
+
+		photometric := uint32(1) // BlackIsZero
+		if samples == 3 {
+			photometric = 2 // RGB
+		}
+		subfile := uint32(0)
+		if len(pages) > 1 {
+			subfile = tiffPage
+		}
+		b := page.Bounds()
+		entries := []tiffEntry{
+			{tiffNewSubfileType, tiffLong, 1, subfile},
+			{tiffImageWidth, tiffLong, 1, uint32(b.Dx())},
+			{tiffImageLength, tiffLong, 1, uint32(b.Dy())},
+			{tiffBitsPerSample, tiffShort, uint32(samples), bitsValue},
+			{tiffCompression, tiffShort, 1, 1},
+			{tiffPhotometric, tiffShort, 1, photometric},
+			{tiffStripOffsets, tiffLong, 1, dataOffset},
+			{tiffSamples, tiffShort, 1, uint32(samples)},
+			{tiffRowsPerStrip, tiffLong, 1, uint32(b.Dy())},
+			{tiffStripBytes, tiffLong, 1, uint32(len(pix))},
+			{tiffXResolution, tiffRational, 1, resolutionOffset},
+			{tiffYResolution, tiffRational, 1, resolutionOffset + 8},
+			{tiffResolutionUnit, tiffShort, 1, 2}, // inches
+			// the page number is two shorts, which fit in the value
+			{tiffPageNumber, tiffShort, 2, uint32(i) | uint32(len(pages))<<16},
+		}
+
+		var ifd bytes.Buffer
+		_ = binary.Write(&ifd, le, uint16(entryCount))
+		_ = binary.Write(&ifd, le, entries)
+		_ = binary.Write(&ifd, le, next)
+
+		padding := make([]byte, ifdOffset-dataOffset-uint32(len(pix)))
+		for _, data := range [][]byte{pix, padding, ifd.Bytes(), extra.Bytes()} {
+			if _, err := out.Write(data); err != nil {
+				return err
+			}
+		}
+		offset = extraOffset + uint32(extra.Len())
+	}
+	return out.Flush()
+}
+
+// tiffFormat returns the bits in each sample and samples per pixel that an image is written with.
+func tiffFormat(img image.Image) (int, int) {
+	switch img.(type) {
+	case *image.Gray:
+		return 8, 1
+	case *image.Gray16:
+		return 16, 1
+	}
+	return 8, 3
+}
+
+// tiffSize returns the number of bytes of pixel data that an image is written with.
+func tiffSize(img image.Image) uint32 {
+	bits, samples := tiffFormat(img)
+	b := img.Bounds()
+	return uint32(b.Dx() * b.Dy() * samples * bits / 8)
+}
+
+// tiffPixels returns the samples of an image in rows, in the format given by tiffFormat.
+func tiffPixels(img image.Image) []byte {
+	b := img.Bounds()
+	switch src := img.(type) {
+	case *image.Gray:
+		pix := make([]byte, 0, b.Dx()*b.Dy())
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i := src.PixOffset(b.Min.X, y)
+			pix = append(pix, src.Pix[i:i+b.Dx()]...)
+		}
+		return pix
+	case *image.Gray16:
+		pix := make([]byte, 0, 2*b.Dx()*b.Dy())
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				v := src.Gray16At(x, y).Y
+				pix = append(pix, byte(v), byte(v>>8))
+			}
+		}
+		return pix
+	}
+
+	pix := make([]byte, 0, 3*b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			pix = append(pix, byte(r>>8), byte(g>>8), byte(bl>>8))
+		}
+	}
+	return pix
+}
+
+// even rounds an offset up to a word boundary, as TIFF requires for directories.
+func even(offset uint32) uint32 {
+	return offset + offset%2
+}
@@ -0,0 +1,138 @@
+package dicomgraphics
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Transform is a display orientation: the image is mirrored horizontally if Flip is set,
+// then rotated clockwise by Rotation degrees, which is a multiple of 90.
+// A presentation state rotates before it flips, so its Image Rotation is reversed when the image is flipped.
+type Transform struct {
+	Rotation int
+	Flip     bool
+}
+
+// ParseRotation checks that a rotation is a multiple of 90 degrees, returning it in the range 0 to 270.
+func ParseRotation(degrees int) (int, bool) {
+	if degrees%90 != 0 {
+		return 0, false
+	}
+	return normaliseRotation(degrees), true
+}
+
+// Then returns the transform that applies t followed by u.
+func (t Transform) Then(u Transform) Transform {
+	// mirroring reverses the direction of an earlier rotation
+	rotation := t.Rotation
+	if u.Flip {
+		rotation = -rotation
+	}
+	return Transform{Rotation: normaliseRotation(rotation + u.Rotation), Flip: t.Flip != u.Flip}
+}
+
+// Rotate returns the transform with the displayed image turned clockwise by a multiple of 90 degrees.
+func (t Transform) Rotate(degrees int) Transform {
+	return t.Then(Transform{Rotation: degrees})
+}
+
+// FlipHorizontal returns the transform with the displayed image mirrored left to right.
+func (t Transform) FlipHorizontal() Transform {
+	return t.Then(Transform{Flip: true})
+}
+
+// FlipVertical returns the transform with the displayed image mirrored top to bottom.
+func (t Transform) FlipVertical() Transform {
+	return t.Then(Transform{Rotation: 180, Flip: true})
+}
+
+// Inverse returns the transform that undoes t.
+func (t Transform) Inverse() Transform {
+	if t.Flip {
+		// a mirrored rotation is its own inverse
+		return t
+	}
+	return Transform{Rotation: normaliseRotation(-t.Rotation)}
+}
+
+// Swapped returns true if the transform exchanges the width and height of an image.
+func (t Transform) Swapped() bool {
+	return normaliseRotation(t.Rotation)%180 != 0
+}
+
+// Size returns the size of an image of w by h after it is transformed.
+func (t Transform) Size(w, h int) (int, int) {
+	if t.Swapped() {
+		return h, w
+	}
+	return w, h
+}
+
+// Point returns where a point in an image of size w by h moves to when the image is transformed.
+func (t Transform) Point(p Point, w, h int) Point {
+	if t.Flip {
+		p.X = float64(w) - p.X
+	}
+
+	switch normaliseRotation(t.Rotation) {
+	case 90:
+		return Point{X: float64(h) - p.Y, Y: p.X}
+	case 180:
+		return Point{X: float64(w) - p.X, Y: float64(h) - p.Y}
+	case 270:
+		return Point{X: p.Y, Y: float64(w) - p.X}
+	}
+	return p
+}
+
+// Apply returns a transformed copy of an image, or the image itself if it is an RGBA image that does not change.
+func (t Transform) Apply(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	rgba, ok := src.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+		b = rgba.Bounds()
+	}
+	if t == (Transform{}) {
+		return rgba
+	}
+
+	dw, dh := t.Size(b.Dx(), b.Dy())
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	t.each(b, func(x, y, dx, dy int) {
+		dst.SetRGBA(dx, dy, rgba.RGBAAt(x, y))
+	})
+	return dst
+}
+
+// ApplyGray16 returns a transformed copy of a 16 bit greyscale image, or the image itself if it does not change.
+func (t Transform) ApplyGray16(src *image.Gray16) *image.Gray16 {
+	if t == (Transform{}) {
+		return src
+	}
+
+	b := src.Bounds()
+	dw, dh := t.Size(b.Dx(), b.Dy())
+	dst := image.NewGray16(image.Rect(0, 0, dw, dh))
+	t.each(b, func(x, y, dx, dy int) {
+		dst.SetGray16(dx, dy, src.Gray16At(x, y))
+	})
+	return dst
+}
+
+// each calls fn with every pixel of the bounds and where it is moved to in a transformed image starting at 0,0.
+func (t Transform) each(b image.Rectangle, fn func(x, y, dx, dy int)) {
+	w, h := b.Dx(), b.Dy()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// transform the centre of the pixel, which lands on the centre of its destination
+			p := t.Point(Point{X: float64(x) + 0.5, Y: float64(y) + 0.5}, w, h)
+			fn(b.Min.X+x, b.Min.Y+y, int(p.X), int(p.Y))
+		}
+	}
+}
+
+func normaliseRotation(degrees int) int {
+	return (degrees%360 + 360) % 360
+}
@@ -0,0 +1,84 @@
+package dicomgraphics
+
+import "testing"
+
+func TestTransformCompose(t *testing.T) {
+	rotate := func(degrees int) func(Transform) Transform {
+		return func(t Transform) Transform { return t.Rotate(degrees) }
+	}
+	flipH := Transform.FlipHorizontal
+	flipV := Transform.FlipVertical
+
+	for _, tt := range []struct {
+		name    string
+		ops     []func(Transform) Transform
+		want    Transform
+		swapped bool
+	}{
+		{"none", nil, Transform{}, false},
+		{"rotate", []func(Transform) Transform{rotate(90)}, Transform{Rotation: 90}, true},
+		{"rotate twice", []func(Transform) Transform{rotate(90), rotate(90)}, Transform{Rotation: 180}, false},
+		{"rotate back", []func(Transform) Transform{rotate(90), rotate(-90)}, Transform{}, false},
+		{"rotate anticlockwise", []func(Transform) Transform{rotate(-90)}, Transform{Rotation: 270}, true},
+		{"rotate past a turn", []func(Transform) Transform{rotate(450)}, Transform{Rotation: 90}, true},
+		{"flip horizontal", []func(Transform) Transform{flipH}, Transform{Flip: true}, false},
+		{"flip horizontal twice", []func(Transform) Transform{flipH, flipH}, Transform{}, false},
+		{"flip vertical", []func(Transform) Transform{flipV}, Transform{Rotation: 180, Flip: true}, false},
+		{"flip vertical twice", []func(Transform) Transform{flipV, flipV}, Transform{}, false},
+		{"flip both ways", []func(Transform) Transform{flipH, flipV}, Transform{Rotation: 180}, false},
+		{"rotate then flip", []func(Transform) Transform{rotate(90), flipH}, Transform{Rotation: 270, Flip: true}, true},
+		{"flip then rotate", []func(Transform) Transform{flipH, rotate(90)}, Transform{Rotation: 90, Flip: true}, true},
+		{"rotate then flip vertical", []func(Transform) Transform{rotate(90), flipV}, Transform{Rotation: 90, Flip: true}, true},
+		{"flip undoes rotation", []func(Transform) Transform{rotate(90), flipH, rotate(90), flipH}, Transform{}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Transform{}
+			for _, op := range tt.ops {
+				got = op(got)
+			}
+			if got != tt.want {
+				t.Errorf("transform = %+v, want %+v", got, tt.want)
+			}
+			if got.Swapped() != tt.swapped {
+				t.Errorf("Swapped() = %v, want %v", got.Swapped(), tt.swapped)
+			}
+
+			// the combined transform moves points as each step does in turn, and its inverse moves them back
+			const w, h = 4, 2
+			for _, p := range []Point{{0, 0}, {4, 0}, {0, 2}, {1, 0.5}} {
+				want, sw, sh := p, w, h
+				for _, op := range tt.ops {
+					step := op(Transform{})
+					want = step.Point(want, sw, sh)
+					sw, sh = step.Size(sw, sh)
+				}
+				if dw, dh := got.Size(w, h); dw != sw || dh != sh {
+					t.Errorf("Size() = %d, %d, want %d, %d", dw, dh, sw, sh)
+				}
+				if moved := got.Point(p, w, h); !closeTo(moved.X, want.X) || !closeTo(moved.Y, want.Y) {
+					t.Errorf("Point(%v) = %v, want %v", p, moved, want)
+				}
+				if back := got.Inverse().Point(want, sw, sh); !closeTo(back.X, p.X) || !closeTo(back.Y, p.Y) {
+					t.Errorf("Inverse().Point(%v) = %v, want %v", want, back, p)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRotation(t *testing.T) {
+	for _, tt := range []struct {
+		in, want int
+		ok       bool
+	}{
+		{0, 0, true},
+		{90, 90, true},
+		{-90, 270, true},
+		{630, 270, true},
+		{45, 0, false},
+	} {
+		if got, ok := ParseRotation(tt.in); got != tt.want || ok != tt.ok {
+			t.Errorf("ParseRotation(%d) = %d, %v, want %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package dicomgraphics
+
+import "strings"
+
+// WindowPreset is a named window for viewing a type of tissue in CT images.
+type WindowPreset struct {
+	Name         string
+	Level, Width int16
+}
+
+// WindowPresets are the common CT windows, in the order they are offered to users.
+var WindowPresets = []WindowPreset{
+	{Name: "Abdomen", Level: 40, Width: 400},
+	{Name: "Bone", Level: 400, Width: 1800},
+	{Name: "Brain", Level: 40, Width: 80},
+	{Name: "Lungs", Level: -600, Width: 1500},
+	{Name: "Mediastinum", Level: 50, Width: 350},
+}
+
+// FindWindowPreset returns the preset with the given name, ignoring case.
+func FindWindowPreset(name string) (WindowPreset, bool) {
+	for _, p := range WindowPresets {
+		if strings.EqualFold(p.Name, strings.TrimSpace(name)) {
+			return p, true
+		}
+	}
+	return WindowPreset{}, false
+}